@@ -0,0 +1,243 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Bag is an immutable multiset that records how many times each value occurs.
+// Iteration visits each distinct value along with its count.
+type Bag[T any] interface {
+	Add(value T, count int) Bag[T]
+	Remove(value T, count int) Bag[T]
+	Count(value T) int
+	Contains(value T) bool
+	DistinctSize() int
+	TotalSize() int
+	Union(other Bag[T]) Bag[T]
+	Intersection(other Bag[T]) Bag[T]
+	Sum(other Bag[T]) Bag[T]
+	Iterate() MapIterator[T, int]
+	ForEach(v MapVisitor[T, int])
+	All() iter.Seq2[T, int]
+	checkInvariants(report reporter)
+}
+
+// bagImpl stores the count of each value as its value in a trie.  Every count in
+// the trie is positive.  distinct is the number of keys in the trie and total is
+// the sum of the counts.
+type bagImpl[T any] struct {
+	hash     HashFunc[T]
+	equals   EqualsFunc[T]
+	layout   *layoutToken
+	root     *node[T, int]
+	distinct int
+	total    int
+}
+
+// NewBag creates an empty Bag whose values are hashed and compared using the
+// provided functions.
+func NewBag[T any](hash HashFunc[T], equals EqualsFunc[T]) Bag[T] {
+	return &bagImpl[T]{hash: hash, equals: equals, layout: newLayoutToken(), root: emptyNode[T, int]()}
+}
+
+// NewComparableBag creates an empty Bag for values that can be compared using ==.
+func NewComparableBag[T comparable]() Bag[T] {
+	return NewBag[T](comparableHash[T](), comparableEquals[T])
+}
+
+func (this *bagImpl[T]) withRoot(newRoot *node[T, int], distinctDelta int, totalDelta int) *bagImpl[T] {
+	if newRoot == nil {
+		newRoot = emptyNode[T, int]()
+	}
+	newBag := *this
+	newBag.root = newRoot
+	newBag.distinct += distinctDelta
+	newBag.total += totalDelta
+	return &newBag
+}
+
+func (this *bagImpl[T]) sameLayout(b Bag[T]) (*bagImpl[T], bool) {
+	other, ok := b.(*bagImpl[T])
+	return other, ok && other.layout == this.layout
+}
+
+// Add increases the count of value by count.  The receiver is returned if count is
+// not positive.
+func (this *bagImpl[T]) Add(value T, count int) Bag[T] {
+	if count <= 0 {
+		return this
+	}
+	newRoot, delta := this.root.update(this.hash(value), 0, value, func(oldCount int, _ bool) (int, bool) {
+		return oldCount + count, true
+	}, nil, this.equals)
+	return this.withRoot(newRoot, delta, count)
+}
+
+// Remove decreases the count of value by count and removes value once its count
+// reaches zero.  The receiver is returned if count is not positive or value is not
+// in the bag.
+func (this *bagImpl[T]) Remove(value T, count int) Bag[T] {
+	if count <= 0 {
+		return this
+	}
+	removed := 0
+	newRoot, delta := this.root.update(this.hash(value), 0, value, func(oldCount int, _ bool) (int, bool) {
+		removed = min(oldCount, count)
+		return oldCount - removed, oldCount > removed
+	}, nil, this.equals)
+	if newRoot == this.root {
+		return this
+	}
+	return this.withRoot(newRoot, delta, -removed)
+}
+
+// Count returns the number of times value occurs in the bag.
+func (this *bagImpl[T]) Count(value T) int {
+	count, _ := this.root.get(this.hash(value), 0, value, this.equals)
+	return count
+}
+
+func (this *bagImpl[T]) Contains(value T) bool {
+	return this.root.contains(this.hash(value), 0, value, this.equals)
+}
+
+// DistinctSize returns the number of distinct values in the bag.
+func (this *bagImpl[T]) DistinctSize() int {
+	return this.distinct
+}
+
+// TotalSize returns the sum of the counts of every value in the bag.
+func (this *bagImpl[T]) TotalSize() int {
+	return this.total
+}
+
+// Union returns a bag in which the count of each value is the larger of its
+// counts in the two bags.  When both bags were derived from the same original bag
+// subtrees found in both are reused without visiting their values.
+func (this *bagImpl[T]) Union(b Bag[T]) Bag[T] {
+	if other, ok := this.sameLayout(b); ok {
+		added := 0
+		merger := &nodeMerger[T, int]{
+			equals: this.equals,
+			resolve: func(_ T, left int, right int) (int, bool) {
+				added += max(right-left, 0)
+				return max(left, right), true
+			},
+			sharedNodes: keepSharedNodes,
+			unmatched: func(_ T, count int) {
+				added += count
+			},
+		}
+		newRoot, delta := merger.merge(this.root, other.root)
+		if newRoot == this.root {
+			return this
+		}
+		return this.withRoot(newRoot, delta, added)
+	}
+
+	var answer Bag[T] = this
+	b.ForEach(func(value T, count int) {
+		answer = answer.Add(value, count-this.Count(value))
+	})
+	return answer
+}
+
+// Intersection returns a bag in which the count of each value is the smaller of
+// its counts in the two bags.  When both bags were derived from the same original
+// bag subtrees found in both are reused without visiting their values.
+func (this *bagImpl[T]) Intersection(b Bag[T]) Bag[T] {
+	var newRoot *node[T, int]
+	var delta int
+	total := this.total
+	if other, ok := this.sameLayout(b); ok {
+		merger := &nodeMerger[T, int]{
+			equals: this.equals,
+			resolve: func(_ T, left int, right int) (int, bool) {
+				total -= max(left-right, 0)
+				return min(left, right), true
+			},
+			sharedNodes: keepSharedNodes,
+			unmatched: func(_ T, count int) {
+				total -= count
+			},
+		}
+		newRoot, delta = merger.intersect(this.root, other.root)
+	} else {
+		total = 0
+		newRoot, delta = this.root.mapValues(func(value T, count int) (int, bool) {
+			count = min(count, b.Count(value))
+			total += count
+			return count, count > 0
+		}, nil)
+	}
+	if newRoot == this.root {
+		return this
+	}
+	return this.withRoot(newRoot, delta, total-this.total)
+}
+
+// Sum returns a bag in which the count of each value is the sum of its counts in
+// the two bags.
+func (this *bagImpl[T]) Sum(b Bag[T]) Bag[T] {
+	if other, ok := this.sameLayout(b); ok {
+		merger := &nodeMerger[T, int]{equals: this.equals, resolve: sumCount[T], sharedNodes: resolveSharedNodes}
+		newRoot, delta := merger.merge(this.root, other.root)
+		if newRoot == this.root {
+			return this
+		}
+		return this.withRoot(newRoot, delta, other.total)
+	}
+
+	var answer Bag[T] = this
+	b.ForEach(func(value T, count int) {
+		answer = answer.Add(value, count)
+	})
+	return answer
+}
+
+func sumCount[T any](_ T, left int, right int) (int, bool) {
+	return left + right, true
+}
+
+func (this *bagImpl[T]) Iterate() MapIterator[T, int] {
+	return &mapIteratorImpl[T, int]{state: this.root.createIteratorState(nil)}
+}
+
+func (this *bagImpl[T]) ForEach(v MapVisitor[T, int]) {
+	this.root.forEach(v)
+}
+
+// All returns an iterator over the distinct values of the bag and their counts for
+// use with range.
+func (this *bagImpl[T]) All() iter.Seq2[T, int] {
+	return func(yield func(T, int) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *bagImpl[T]) checkInvariants(report reporter) {
+	this.root.checkInvariants(this.hash, this.equals, 0, report)
+	distinct := 0
+	total := 0
+	for value, count := range this.All() {
+		if count <= 0 {
+			report(fmt.Sprintf("non-positive count detected: value=%v count=%d", value, count))
+		}
+		if actual := this.Count(value); actual != count {
+			report(fmt.Sprintf("Count returned incorrect result: value=%v expected=%d actual=%d", value, count, actual))
+		}
+		distinct++
+		total += count
+	}
+	if distinct != this.distinct {
+		report(fmt.Sprintf("DistinctSize() does not match number of values in iterator: expected=%d actual=%d", this.distinct, distinct))
+	}
+	if total != this.total {
+		report(fmt.Sprintf("TotalSize() does not match sum of counts: expected=%d actual=%d", this.total, total))
+	}
+}
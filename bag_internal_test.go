@@ -0,0 +1,159 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func verifyBag(t *testing.T, b Bag[int], expected map[int]int) {
+	b.checkInvariants(createReporter(t))
+	total := 0
+	for value, count := range expected {
+		total += count
+		if actual := b.Count(value); actual != count {
+			t.Error(fmt.Sprintf("Count mismatch: value=%d expected=%d actual=%d", value, count, actual))
+		}
+	}
+	if b.DistinctSize() != len(expected) || b.TotalSize() != total {
+		t.Error(fmt.Sprintf("size mismatch: expected=%d,%d actual=%d,%d", len(expected), total, b.DistinctSize(), b.TotalSize()))
+	}
+}
+
+func randomBag(random *rand.Rand, b Bag[int], expected map[int]int) Bag[int] {
+	for i := 0; i < 2000; i++ {
+		value := random.Intn(300)
+		count := random.Intn(5)
+		if random.Intn(3) == 0 {
+			b = b.Remove(value, count)
+			expected[value] = max(0, expected[value]-count)
+			if expected[value] == 0 {
+				delete(expected, value)
+			}
+		} else {
+			b = b.Add(value, count)
+			if count > 0 {
+				expected[value] += count
+			}
+		}
+	}
+	return b
+}
+
+func TestBag(t *testing.T) {
+	random := rand.New(rand.NewSource(25))
+	empty := NewComparableBag[int]()
+	leftCounts := make(map[int]int)
+	rightCounts := make(map[int]int)
+	left := randomBag(random, empty, leftCounts)
+	right := randomBag(random, empty, rightCounts)
+	verifyBag(t, left, leftCounts)
+	verifyBag(t, right, rightCounts)
+
+	union := make(map[int]int)
+	intersection := make(map[int]int)
+	sum := make(map[int]int)
+	for value, count := range leftCounts {
+		union[value] = max(count, rightCounts[value])
+		sum[value] = count + rightCounts[value]
+		if rightCounts[value] > 0 {
+			intersection[value] = min(count, rightCounts[value])
+		}
+	}
+	for value, count := range rightCounts {
+		if leftCounts[value] == 0 {
+			union[value] = count
+			sum[value] = count
+		}
+	}
+	verifyBag(t, left.Union(right), union)
+	verifyBag(t, left.Intersection(right), intersection)
+	verifyBag(t, left.Sum(right), sum)
+
+	other := NewComparableBag[int]()
+	for value, count := range right.All() {
+		other = other.Add(value, count)
+	}
+	verifyBag(t, left.Union(other), union)
+	verifyBag(t, left.Intersection(other), intersection)
+	verifyBag(t, left.Sum(other), sum)
+	verifyBag(t, left, leftCounts)
+}
+
+func TestBagCounts(t *testing.T) {
+	b := NewComparableBag[string]().Add("a", 3).Add("b", 1).Add("a", 2)
+	if b.Count("a") != 5 || b.Count("c") != 0 || b.DistinctSize() != 2 || b.TotalSize() != 6 {
+		t.Error(fmt.Sprintf("count mismatch: a=%d total=%d", b.Count("a"), b.TotalSize()))
+	}
+	if b.Add("a", 0) != b || b.Remove("c", 1) != b || b.Remove("a", -1) != b {
+		t.Error("unchanged bag was copied")
+	}
+	removed := b.Remove("a", 10).Remove("b", 1)
+	removed.checkInvariants(createReporter(t))
+	if removed.Contains("a") || removed.DistinctSize() != 0 || removed.TotalSize() != 0 {
+		t.Error(fmt.Sprintf("expected empty bag but got %d values", removed.DistinctSize()))
+	}
+	doubled := b.Sum(b)
+	doubled.checkInvariants(createReporter(t))
+	if doubled.Count("a") != 10 || doubled.TotalSize() != 12 || b.Union(b) != b || b.Intersection(b) != b {
+		t.Error("combining a bag with itself returned incorrect result")
+	}
+}
+
+func TestBagSkipsSharedSubtrees(t *testing.T) {
+	base := NewComparableBag[int]()
+	for i := 0; i < 2000; i++ {
+		base = base.Add(i, i%3+1)
+	}
+	a := base.Add(5000, 3)
+
+	// replace the descendants of every subtree shared by both bags with nil so that
+	// visiting the counts in a shared subtree panics
+	baseRoot, aRoot := base.(*bagImpl[int]).root, a.(*bagImpl[int]).root
+	poisoned := 0
+	for i, child := range aRoot.children {
+		if child == baseRoot.children[i] && child.children != nil {
+			child.children = make([]*node[int, int], len(child.children))
+			poisoned++
+		}
+	}
+	if poisoned == 0 {
+		t.Fatal("no shared subtrees to poison")
+	}
+
+	if a.Union(base) != a || base.Intersection(a) != base {
+		t.Error("unchanged bag was copied")
+	}
+	if union := base.Union(a); union.DistinctSize() != a.DistinctSize() || union.TotalSize() != a.TotalSize() || union.Count(5000) != 3 {
+		t.Error(fmt.Sprintf("union mismatch: distinct=%d total=%d", union.DistinctSize(), union.TotalSize()))
+	}
+	if intersection := a.Intersection(base); intersection.DistinctSize() != base.DistinctSize() || intersection.TotalSize() != base.TotalSize() || intersection.Contains(5000) {
+		t.Error(fmt.Sprintf("intersection mismatch: distinct=%d total=%d", intersection.DistinctSize(), intersection.TotalSize()))
+	}
+}
+
+func TestBagIntersectionHashesOnce(t *testing.T) {
+	hashes := 0
+	intHash := comparableHash[int]()
+	other := NewBag[int](func(value int) HashCode {
+		hashes++
+		return intHash(value)
+	}, comparableEquals[int])
+	b := NewComparableBag[int]()
+	for i := 0; i < 500; i++ {
+		b = b.Add(i, 2)
+		if i%2 == 0 {
+			other = other.Add(i, 1)
+		}
+	}
+
+	hashes = 0
+	intersection := b.Intersection(other)
+	intersection.checkInvariants(createReporter(t))
+	if intersection.DistinctSize() != 250 || intersection.TotalSize() != 250 {
+		t.Error(fmt.Sprintf("intersection mismatch: distinct=%d total=%d", intersection.DistinctSize(), intersection.TotalSize()))
+	}
+	if hashes != b.DistinctSize() {
+		t.Error(fmt.Sprintf("expected one hash per value: expected=%d actual=%d", b.DistinctSize(), hashes))
+	}
+}
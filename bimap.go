@@ -0,0 +1,186 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// BiMap is an immutable map whose values are unique so that keys can also be found
+// from their values.  Inverse returns the same associations with keys and values
+// exchanged.
+type BiMap[K any, V any] interface {
+	Assign(key K, value V) BiMap[K, V]
+	TryAssign(key K, value V) (BiMap[K, V], bool)
+	Get(key K) V
+	Lookup(key K) (V, bool)
+	LookupKey(value V) (K, bool)
+	ContainsKey(key K) bool
+	ContainsValue(value V) bool
+	Delete(key K) BiMap[K, V]
+	DeleteValue(value V) BiMap[K, V]
+	Keys() Set[K]
+	Values() Set[V]
+	Inverse() BiMap[V, K]
+	Size() int
+	Iterate() MapIterator[K, V]
+	ForEach(v MapVisitor[K, V])
+	All() iter.Seq2[K, V]
+	checkInvariants(report reporter)
+}
+
+// BiMapPolicy determines what Assign does when the value is already assigned to a
+// different key.
+type BiMapPolicy int
+
+const (
+	// BiMapReplace removes the other key so the value can be assigned to the new key.
+	BiMapReplace BiMapPolicy = iota
+	// BiMapReject leaves the map unchanged.
+	BiMapReject
+)
+
+// biMapImpl keeps a forward map from keys to values and an inverse map from values
+// to keys that always hold the same associations.
+type biMapImpl[K any, V any] struct {
+	forward     Map[K, V]
+	inverse     Map[V, K]
+	keyEquals   EqualsFunc[K]
+	valueEquals EqualsFunc[V]
+	policy      BiMapPolicy
+}
+
+// NewBiMap creates an empty BiMap whose keys and values are hashed and compared
+// using the provided functions.
+func NewBiMap[K any, V any](keyHash HashFunc[K], keyEquals EqualsFunc[K], valueHash HashFunc[V], valueEquals EqualsFunc[V], policy BiMapPolicy) BiMap[K, V] {
+	return &biMapImpl[K, V]{
+		forward:     NewMap[K, V](keyHash, keyEquals),
+		inverse:     NewMap[V, K](valueHash, valueEquals),
+		keyEquals:   keyEquals,
+		valueEquals: valueEquals,
+		policy:      policy,
+	}
+}
+
+// NewComparableBiMap creates an empty BiMap for keys and values that can be
+// compared using ==.
+func NewComparableBiMap[K comparable, V comparable](policy BiMapPolicy) BiMap[K, V] {
+	return NewBiMap[K, V](comparableHash[K](), comparableEquals[K], comparableHash[V](), comparableEquals[V], policy)
+}
+
+func (this *biMapImpl[K, V]) withMaps(forward Map[K, V], inverse Map[V, K]) *biMapImpl[K, V] {
+	newMap := *this
+	newMap.forward = forward
+	newMap.inverse = inverse
+	return &newMap
+}
+
+// Assign associates key with value, replacing any value previously assigned to key.
+// If value is assigned to a different key the policy decides whether that key is
+// removed or the receiver is returned unchanged.  The receiver is also returned if
+// key already has value so use TryAssign to learn whether a value was rejected.
+func (this *biMapImpl[K, V]) Assign(key K, value V) BiMap[K, V] {
+	answer, _ := this.TryAssign(key, value)
+	return answer
+}
+
+// TryAssign is like Assign but its second result is false if the policy is
+// BiMapReject and value is already assigned to a different key.
+func (this *biMapImpl[K, V]) TryAssign(key K, value V) (BiMap[K, V], bool) {
+	forward := this.forward
+	inverse := this.inverse
+	if owner, ok := inverse.Lookup(value); ok {
+		if this.keyEquals(owner, key) {
+			return this, true
+		} else if this.policy == BiMapReject {
+			return this, false
+		}
+		forward = forward.Delete(owner)
+	}
+	if oldValue, ok := forward.Lookup(key); ok {
+		inverse = inverse.Delete(oldValue)
+	}
+	return this.withMaps(forward.Assign(key, value), inverse.Assign(value, key)), true
+}
+
+func (this *biMapImpl[K, V]) Get(key K) V {
+	return this.forward.Get(key)
+}
+
+func (this *biMapImpl[K, V]) Lookup(key K) (V, bool) {
+	return this.forward.Lookup(key)
+}
+
+// LookupKey returns the key assigned value and true, or the zero value and false if
+// no key has the value.
+func (this *biMapImpl[K, V]) LookupKey(value V) (K, bool) {
+	return this.inverse.Lookup(value)
+}
+
+func (this *biMapImpl[K, V]) ContainsKey(key K) bool {
+	return this.forward.ContainsKey(key)
+}
+
+func (this *biMapImpl[K, V]) ContainsValue(value V) bool {
+	return this.inverse.ContainsKey(value)
+}
+
+func (this *biMapImpl[K, V]) Delete(key K) BiMap[K, V] {
+	value, ok := this.forward.Lookup(key)
+	if !ok {
+		return this
+	}
+	return this.withMaps(this.forward.Delete(key), this.inverse.Delete(value))
+}
+
+// DeleteValue removes the key assigned value.
+func (this *biMapImpl[K, V]) DeleteValue(value V) BiMap[K, V] {
+	key, ok := this.inverse.Lookup(value)
+	if !ok {
+		return this
+	}
+	return this.withMaps(this.forward.Delete(key), this.inverse.Delete(value))
+}
+
+func (this *biMapImpl[K, V]) Keys() Set[K] {
+	return this.forward.Keys()
+}
+
+func (this *biMapImpl[K, V]) Values() Set[V] {
+	return this.inverse.Keys()
+}
+
+// Inverse returns a BiMap from the values of this map to their keys.  It shares
+// both maps with this map and uses the same policy.
+func (this *biMapImpl[K, V]) Inverse() BiMap[V, K] {
+	return &biMapImpl[V, K]{forward: this.inverse, inverse: this.forward, keyEquals: this.valueEquals, valueEquals: this.keyEquals, policy: this.policy}
+}
+
+func (this *biMapImpl[K, V]) Size() int {
+	return this.forward.Size()
+}
+
+func (this *biMapImpl[K, V]) Iterate() MapIterator[K, V] {
+	return this.forward.Iterate()
+}
+
+func (this *biMapImpl[K, V]) ForEach(v MapVisitor[K, V]) {
+	this.forward.ForEach(v)
+}
+
+// All returns an iterator over the keys and values of the map for use with range.
+func (this *biMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return this.forward.All()
+}
+
+func (this *biMapImpl[K, V]) checkInvariants(report reporter) {
+	this.forward.checkInvariants(report)
+	this.inverse.checkInvariants(report)
+	if this.forward.Size() != this.inverse.Size() {
+		report(fmt.Sprintf("forward and inverse sizes differ: forward=%d inverse=%d", this.forward.Size(), this.inverse.Size()))
+	}
+	this.forward.ForEach(func(key K, value V) {
+		if owner, ok := this.inverse.Lookup(value); !ok || !this.keyEquals(owner, key) {
+			report(fmt.Sprintf("inverse does not match forward: key=%v value=%v owner=%v", key, value, owner))
+		}
+	})
+}
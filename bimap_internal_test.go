@@ -0,0 +1,97 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestBiMap(t *testing.T) {
+	m := NewComparableBiMap[int, string](BiMapReplace)
+	forward := make(map[int]string)
+	inverse := make(map[string]int)
+	random := rand.New(rand.NewSource(24))
+	for i := 0; i < 5000; i++ {
+		key := random.Intn(200)
+		value := val(random.Intn(200))
+		switch random.Intn(4) {
+		case 0:
+			m = m.Delete(key)
+			if oldValue, ok := forward[key]; ok {
+				delete(inverse, oldValue)
+				delete(forward, key)
+			}
+		case 1:
+			m = m.DeleteValue(value)
+			if owner, ok := inverse[value]; ok {
+				delete(forward, owner)
+				delete(inverse, value)
+			}
+		default:
+			m = m.Assign(key, value)
+			if owner, ok := inverse[value]; ok {
+				delete(forward, owner)
+			}
+			if oldValue, ok := forward[key]; ok {
+				delete(inverse, oldValue)
+			}
+			forward[key] = value
+			inverse[value] = key
+		}
+		if i%500 == 0 {
+			m.checkInvariants(createReporter(t))
+		}
+	}
+	m.checkInvariants(createReporter(t))
+
+	if m.Size() != len(forward) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(forward), m.Size()))
+	}
+	for key, value := range forward {
+		if m.Get(key) != value {
+			t.Error(fmt.Sprintf("Get mismatch: key=%d expected=%s actual=%s", key, value, m.Get(key)))
+		}
+		if owner, ok := m.LookupKey(value); !ok || owner != key {
+			t.Error(fmt.Sprintf("LookupKey mismatch: value=%s expected=%d actual=%d", value, key, owner))
+		}
+	}
+}
+
+func TestBiMapPolicyAndInverse(t *testing.T) {
+	replace := NewComparableBiMap[string, int](BiMapReplace).Assign("a", 1).Assign("b", 2)
+	reject := NewComparableBiMap[string, int](BiMapReject).Assign("a", 1).Assign("b", 2)
+
+	replaced := replace.Assign("c", 1)
+	replaced.checkInvariants(createReporter(t))
+	if replaced.ContainsKey("a") || replaced.Get("c") != 1 || replaced.Size() != 2 {
+		t.Error(fmt.Sprintf("replace policy mismatch: size=%d", replaced.Size()))
+	}
+	if reject.Assign("c", 1) != reject || reject.Assign("a", 1) != reject {
+		t.Error("reject policy changed the map")
+	}
+	if m, ok := reject.TryAssign("c", 1); ok || m != reject {
+		t.Error("TryAssign did not report a rejected value")
+	}
+	if m, ok := reject.TryAssign("a", 1); !ok || m != reject {
+		t.Error("TryAssign reported an existing association as rejected")
+	}
+	if m, ok := replace.TryAssign("c", 1); !ok || m.Get("c") != 1 || m.ContainsKey("a") {
+		t.Error("TryAssign did not replace the other key")
+	}
+	moved := reject.Assign("a", 3)
+	moved.checkInvariants(createReporter(t))
+	if moved.ContainsValue(1) || moved.Get("a") != 3 {
+		t.Error("assigning a new value did not remove the old value")
+	}
+
+	inverse := replace.Inverse()
+	inverse.checkInvariants(createReporter(t))
+	if inverse.Get(2) != "b" || inverse.Inverse().Get("a") != 1 {
+		t.Error("Inverse returned incorrect associations")
+	}
+	inverse = inverse.Assign(2, "z")
+	inverse.checkInvariants(createReporter(t))
+	if key, _ := inverse.Inverse().LookupKey(2); key != "z" || replace.Get("b") != 2 {
+		t.Error("changing the inverse did not produce a matching map")
+	}
+}
@@ -0,0 +1,141 @@
+package immutableMap
+
+// MapBuilder accumulates changes to a map in place and produces an immutable Map
+// when Build is called.  Nodes created by a builder are modified in place until
+// the next call to Build so bulk construction avoids copying a path of nodes for
+// every change.  A MapBuilder is not safe for concurrent use.
+type MapBuilder[K any, V any] interface {
+	Assign(key K, value V)
+	Get(key K) V
+	Lookup(key K) (V, bool)
+	Delete(key K)
+	Size() int
+	Build() Map[K, V]
+}
+
+// SetBuilder accumulates changes to a set in place and produces an immutable Set
+// when Build is called.  A SetBuilder is not safe for concurrent use.
+type SetBuilder[T any] interface {
+	Add(value T)
+	Contains(value T) bool
+	Delete(value T)
+	Size() int
+	Build() Set[T]
+}
+
+type mapBuilderImpl[K any, V any] struct {
+	hash      HashFunc[K]
+	equals    EqualsFunc[K]
+	valueHash HashFunc[V]
+	layout    *layoutToken
+	root      *node[K, V]
+	size      int
+	edit      *editToken
+}
+
+type setBuilderImpl[T any, V any] struct {
+	hash   HashFunc[T]
+	equals EqualsFunc[T]
+	layout *layoutToken
+	root   *node[T, V]
+	size   int
+	edit   *editToken
+}
+
+// NewMapBuilder creates an empty MapBuilder whose keys are hashed and compared
+// using the provided functions.
+func NewMapBuilder[K any, V any](hash HashFunc[K], equals EqualsFunc[K]) MapBuilder[K, V] {
+	return &mapBuilderImpl[K, V]{hash: hash, equals: equals, layout: newLayoutToken(), root: emptyNode[K, V](), edit: newEditToken()}
+}
+
+// NewSetBuilder creates an empty SetBuilder whose values are hashed and compared
+// using the provided functions.
+func NewSetBuilder[T any](hash HashFunc[T], equals EqualsFunc[T]) SetBuilder[T] {
+	return &setBuilderImpl[T, struct{}]{hash: hash, equals: equals, layout: newLayoutToken(), root: emptyNode[T, struct{}](), edit: newEditToken()}
+}
+
+// ToBuilder returns a MapBuilder initially containing the same entries as this map.
+// The map itself is not affected by changes made using the builder.
+func (this *mapImpl[K, V]) ToBuilder() MapBuilder[K, V] {
+	return &mapBuilderImpl[K, V]{hash: this.hash, equals: this.equals, valueHash: this.valueHash, layout: this.layout, root: this.root, size: this.size, edit: newEditToken()}
+}
+
+// ToBuilder returns a SetBuilder initially containing the same values as this set.
+// The set itself is not affected by changes made using the builder.
+func (this *setImpl[T, V]) ToBuilder() SetBuilder[T] {
+	return &setBuilderImpl[T, V]{hash: this.hash, equals: this.equals, layout: this.layout, root: this.root, size: this.size, edit: newEditToken()}
+}
+
+// emptyBuilder returns an empty SetBuilder that hashes and compares values the same
+// way as this set.  Sets it builds share the layout of this set.
+func (this *setImpl[T, V]) emptyBuilder() SetBuilder[T] {
+	return &setBuilderImpl[T, V]{hash: this.hash, equals: this.equals, layout: this.layout, root: emptyNode[T, V](), edit: newEditToken()}
+}
+
+func (this *mapBuilderImpl[K, V]) Assign(key K, value V) {
+	newRoot, delta := this.root.transientAssign(this.edit, this.hash(key), 0, key, value, hashValue(this.valueHash, value), this.equals)
+	this.root = newRoot
+	this.size += delta
+}
+
+func (this *mapBuilderImpl[K, V]) Get(key K) V {
+	value, _ := this.Lookup(key)
+	return value
+}
+
+func (this *mapBuilderImpl[K, V]) Lookup(key K) (V, bool) {
+	return this.root.get(this.hash(key), 0, key, this.equals)
+}
+
+func (this *mapBuilderImpl[K, V]) Delete(key K) {
+	newRoot, delta := this.root.transientDelete(this.edit, this.hash(key), 0, key, this.equals)
+	if newRoot == nil {
+		newRoot = emptyNode[K, V]()
+	}
+	this.root = newRoot
+	this.size += delta
+}
+
+func (this *mapBuilderImpl[K, V]) Size() int {
+	return this.size
+}
+
+// Build returns an immutable Map containing the builder's current entries.  The
+// builder remains usable but will copy any node it shares with the returned map
+// before modifying it.
+func (this *mapBuilderImpl[K, V]) Build() Map[K, V] {
+	this.edit = newEditToken()
+	return &mapImpl[K, V]{hash: this.hash, equals: this.equals, valueHash: this.valueHash, layout: this.layout, root: this.root, size: this.size}
+}
+
+func (this *setBuilderImpl[T, V]) Add(value T) {
+	var zero V
+	newRoot, delta := this.root.transientAssign(this.edit, this.hash(value), 0, value, zero, 0, this.equals)
+	this.root = newRoot
+	this.size += delta
+}
+
+func (this *setBuilderImpl[T, V]) Contains(value T) bool {
+	return this.root.contains(this.hash(value), 0, value, this.equals)
+}
+
+func (this *setBuilderImpl[T, V]) Delete(value T) {
+	newRoot, delta := this.root.transientDelete(this.edit, this.hash(value), 0, value, this.equals)
+	if newRoot == nil {
+		newRoot = emptyNode[T, V]()
+	}
+	this.root = newRoot
+	this.size += delta
+}
+
+func (this *setBuilderImpl[T, V]) Size() int {
+	return this.size
+}
+
+// Build returns an immutable Set containing the builder's current values.  The
+// builder remains usable but will copy any node it shares with the returned set
+// before modifying it.
+func (this *setBuilderImpl[T, V]) Build() Set[T] {
+	this.edit = newEditToken()
+	return &setImpl[T, V]{hash: this.hash, equals: this.equals, layout: this.layout, root: this.root, size: this.size}
+}
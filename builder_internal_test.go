@@ -0,0 +1,96 @@
+package immutableMap
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMapBuilder(t *testing.T) {
+	b := NewMapBuilder[Object, Object](stringHash, stringEquals)
+	for i := -2000; i <= 2000; i++ {
+		b.Assign(val(i), i)
+	}
+	b.Assign(val(0), -1)
+	if b.Size() != 4001 {
+		t.Error(fmt.Sprintf("expected size 4001 but got %d", b.Size()))
+	}
+	m := b.Build()
+	m.checkInvariants(createReporter(t))
+	verifyValue(t, m, val(0), -1)
+
+	for i := -2000; i <= 0; i++ {
+		b.Delete(val(i))
+	}
+	b.Assign(val(5), "five")
+	m2 := b.Build()
+	m2.checkInvariants(createReporter(t))
+	if m2.Size() != 2000 {
+		t.Error(fmt.Sprintf("expected size 2000 but got %d", m2.Size()))
+	}
+	verifyValue(t, m2, val(5), "five")
+	verifyValue(t, m2, val(-5), nil)
+
+	// changes made after Build must not affect the maps already built
+	m.checkInvariants(createReporter(t))
+	if m.Size() != 4001 {
+		t.Error(fmt.Sprintf("expected size 4001 but got %d", m.Size()))
+	}
+	verifyValue(t, m, val(5), 5)
+	verifyValue(t, m, val(-5), -5)
+}
+
+func TestMapToBuilder(t *testing.T) {
+	m := CreateMap(numberHash, stringEquals)
+	for i := 0; i < 500; i++ {
+		m = m.Assign(val(i), i)
+	}
+	b := m.ToBuilder()
+	for i := 0; i < 500; i += 2 {
+		b.Delete(val(i))
+	}
+	for i := 500; i < 600; i++ {
+		b.Assign(val(i), i)
+	}
+	if v, ok := b.Lookup(val(501)); !ok || v != 501 {
+		t.Error(fmt.Sprintf("Lookup mismatch: value=%v ok=%v", v, ok))
+	}
+	m2 := b.Build()
+	m2.checkInvariants(createReporter(t))
+	if m2.Size() != 350 {
+		t.Error(fmt.Sprintf("expected size 350 but got %d", m2.Size()))
+	}
+
+	m.checkInvariants(createReporter(t))
+	if m.Size() != 500 {
+		t.Error(fmt.Sprintf("expected size 500 but got %d", m.Size()))
+	}
+	verifyValue(t, m, val(0), 0)
+	verifyValue(t, m, val(501), nil)
+}
+
+func TestSetBuilder(t *testing.T) {
+	b := NewSetBuilder[Object](numberHash, stringEquals)
+	for i := 0; i < 1000; i++ {
+		b.Add(val(i))
+	}
+	s := b.Build()
+	s.checkInvariants(createReporter(t))
+
+	b2 := s.ToBuilder()
+	for i := 0; i < 1000; i++ {
+		b2.Delete(val(i))
+		b.Delete(val(i))
+	}
+	if b2.Size() != 0 || b.Size() != 0 {
+		t.Error(fmt.Sprintf("expected empty builders but got sizes %d and %d", b.Size(), b2.Size()))
+	}
+	b2.Build().checkInvariants(createReporter(t))
+
+	s.checkInvariants(createReporter(t))
+	if s.Size() != 1000 {
+		t.Error(fmt.Sprintf("expected size 1000 but got %d", s.Size()))
+	}
+	if !s.Contains(val(999)) {
+		t.Error("built set was modified by builder")
+	}
+}
@@ -0,0 +1,47 @@
+package immutableMap
+
+// FromGoMap creates a Map containing the entries of a native Go map.  The trie is
+// constructed in bulk using a MapBuilder and hashes its keys as NewComparableMap does.
+func FromGoMap[K comparable, V any](m map[K]V) Map[K, V] {
+	builder := NewMapBuilder[K, V](comparableHash[K](), comparableEquals[K])
+	for key, value := range m {
+		builder.Assign(key, value)
+	}
+	return builder.Build()
+}
+
+// ToGoMap returns a new native Go map containing the entries of m.
+func ToGoMap[K comparable, V any](m Map[K, V]) map[K]V {
+	answer := make(map[K]V, m.Size())
+	m.ForEach(func(key K, value V) {
+		answer[key] = value
+	})
+	return answer
+}
+
+// MapFromEntries creates a Map containing the given entries.  When a key appears
+// more than once the last value wins.  The trie is constructed in bulk using a
+// MapBuilder and hashes its keys as NewComparableMap does.
+func MapFromEntries[K comparable, V any](entries []Entry[K, V]) Map[K, V] {
+	builder := NewMapBuilder[K, V](comparableHash[K](), comparableEquals[K])
+	for _, entry := range entries {
+		builder.Assign(entry.Key, entry.Value)
+	}
+	return builder.Build()
+}
+
+// SetFromSlice creates a Set containing the distinct values of a slice.  The trie
+// is constructed in bulk using a SetBuilder and hashes its values as
+// NewComparableSet does.
+func SetFromSlice[T comparable](values []T) Set[T] {
+	builder := NewSetBuilder[T](comparableHash[T](), comparableEquals[T])
+	for _, value := range values {
+		builder.Add(value)
+	}
+	return builder.Build()
+}
+
+// SetToSlice returns a new slice containing the values of s in iteration order.
+func SetToSlice[T any](s Set[T]) []T {
+	return s.ToSlice()
+}
@@ -0,0 +1,57 @@
+package immutableMap
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestGoMapConversions(t *testing.T) {
+	native := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		native[val(i)] = i
+	}
+	m := FromGoMap(native)
+	m.checkInvariants(createReporter(t))
+	if m.Size() != len(native) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(native), m.Size()))
+	}
+	for key, value := range native {
+		if v, ok := m.Lookup(key); !ok || v != value {
+			t.Error(fmt.Sprintf("Lookup mismatch: key=%v value=%v ok=%v", key, v, ok))
+		}
+	}
+
+	back := ToGoMap(m.Delete(val(7)))
+	if len(back) != 999 || back[val(8)] != 8 {
+		t.Error(fmt.Sprintf("ToGoMap mismatch: len=%d", len(back)))
+	}
+	if _, ok := back[val(7)]; ok {
+		t.Error("ToGoMap contained deleted key")
+	}
+
+	entries := m.Entries().ToSlice()
+	entries = append(entries, Entry[string, int]{Key: val(3), Value: -3})
+	fromEntries := MapFromEntries(entries)
+	fromEntries.checkInvariants(createReporter(t))
+	if fromEntries.Size() != 1000 || fromEntries.Get(val(3)) != -3 {
+		t.Error(fmt.Sprintf("MapFromEntries mismatch: size=%d value=%d", fromEntries.Size(), fromEntries.Get(val(3))))
+	}
+}
+
+func TestSliceConversions(t *testing.T) {
+	values := []int{5, 3, 9, 3, 1, 5, 7}
+	s := SetFromSlice(values)
+	s.checkInvariants(createReporter(t))
+	if s.Size() != 5 {
+		t.Error(fmt.Sprintf("expected size 5 but got %d", s.Size()))
+	}
+
+	slice := SetToSlice(s)
+	sort.Ints(slice)
+	assertString(fmt.Sprint(slice), "[1 3 5 7 9]", t)
+
+	if size := len(SetToSlice(SetFromSlice([]string{}))); size != 0 {
+		t.Error(fmt.Sprintf("expected empty slice but got length %d", size))
+	}
+}
@@ -0,0 +1,156 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Deque is an immutable double ended queue.  Values can be pushed onto and popped
+// from either end.  Iteration visits values from front to back.  Pushes and pops
+// take amortized constant time when each version of the deque is pushed or popped
+// at most once.  Popping an older version again can take time proportional to its
+// size.
+type Deque[T any] interface {
+	PushFront(value T) Deque[T]
+	PushBack(value T) Deque[T]
+	PopFront() (Deque[T], T, bool)
+	PopBack() (Deque[T], T, bool)
+	PeekFront() (T, bool)
+	PeekBack() (T, bool)
+	Size() int
+	Iterate() Iterator[T]
+	ForEach(v SetVisitor[T])
+	All() iter.Seq[T]
+	ToSlice() []T
+	Equals(other Deque[T], equals EqualsFunc[T]) bool
+	checkInvariants(report reporter)
+}
+
+// dequeImpl holds values in two stacks.  front holds values from the front of the
+// deque and back holds values from the back in reverse order.  Neither stack is
+// empty while the other holds more than one value.  When one empties half of the
+// other is copied and reversed to become the emptied stack.
+type dequeImpl[T any] struct {
+	front *listNode[T]
+	back  *listNode[T]
+}
+
+// CreateDeque creates an empty Deque.
+func CreateDeque[T any]() Deque[T] {
+	return &dequeImpl[T]{}
+}
+
+func newDeque[T any](front *listNode[T], back *listNode[T]) *dequeImpl[T] {
+	if front == nil && listSize(back) > 1 {
+		back, front = back.split(back.size / 2)
+		front = front.reverseOnto(nil)
+	} else if back == nil && listSize(front) > 1 {
+		front, back = front.split(front.size / 2)
+		back = back.reverseOnto(nil)
+	}
+	return &dequeImpl[T]{front: front, back: back}
+}
+
+func (this *dequeImpl[T]) PushFront(value T) Deque[T] {
+	return newDeque(this.front.push(value), this.back)
+}
+
+func (this *dequeImpl[T]) PushBack(value T) Deque[T] {
+	return newDeque(this.front, this.back.push(value))
+}
+
+// PopFront returns a deque without its front value along with that value.  The
+// final result is false if the deque is empty.
+func (this *dequeImpl[T]) PopFront() (Deque[T], T, bool) {
+	if this.front == nil && this.back == nil {
+		var zero T
+		return this, zero, false
+	} else if this.front == nil {
+		return newDeque(nil, this.back.next), this.back.value, true
+	}
+	return newDeque(this.front.next, this.back), this.front.value, true
+}
+
+// PopBack returns a deque without its back value along with that value.  The final
+// result is false if the deque is empty.
+func (this *dequeImpl[T]) PopBack() (Deque[T], T, bool) {
+	if this.front == nil && this.back == nil {
+		var zero T
+		return this, zero, false
+	} else if this.back == nil {
+		return newDeque(this.front.next, nil), this.front.value, true
+	}
+	return newDeque(this.front, this.back.next), this.back.value, true
+}
+
+// PeekFront returns the front value.  The second result is false if the deque is
+// empty.
+func (this *dequeImpl[T]) PeekFront() (T, bool) {
+	if this.front != nil {
+		return this.front.value, true
+	} else if this.back != nil {
+		return this.back.value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// PeekBack returns the back value.  The second result is false if the deque is
+// empty.
+func (this *dequeImpl[T]) PeekBack() (T, bool) {
+	if this.back != nil {
+		return this.back.value, true
+	} else if this.front != nil {
+		return this.front.value, true
+	}
+	var zero T
+	return zero, false
+}
+
+func (this *dequeImpl[T]) Size() int {
+	return listSize(this.front) + listSize(this.back)
+}
+
+func (this *dequeImpl[T]) Iterate() Iterator[T] {
+	return &listIteratorImpl[T]{current: this.front, reversed: this.back}
+}
+
+func (this *dequeImpl[T]) ForEach(v SetVisitor[T]) {
+	for i := this.Iterate(); i.Next(); {
+		v(i.Get())
+	}
+}
+
+// All returns an iterator over the values of the deque from front to back for use
+// with range.
+func (this *dequeImpl[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *dequeImpl[T]) ToSlice() []T {
+	answer := make([]T, 0, this.Size())
+	for i := this.Iterate(); i.Next(); {
+		answer = append(answer, i.Get())
+	}
+	return answer
+}
+
+// Equals returns true if other holds the same number of values in the same order
+// and equals returns true for each pair of values.
+func (this *dequeImpl[T]) Equals(other Deque[T], equals EqualsFunc[T]) bool {
+	return this.Size() == other.Size() && listsEqual(this.Iterate(), other.Iterate(), equals)
+}
+
+func (this *dequeImpl[T]) checkInvariants(report reporter) {
+	this.front.checkInvariants(report)
+	this.back.checkInvariants(report)
+	if (this.front == nil && listSize(this.back) > 1) || (this.back == nil && listSize(this.front) > 1) {
+		report(fmt.Sprintf("unbalanced deque: front=%d back=%d", listSize(this.front), listSize(this.back)))
+	}
+}
@@ -0,0 +1,72 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestDeque(t *testing.T) {
+	d := CreateDeque[int]()
+	var expected []int
+	random := rand.New(rand.NewSource(22))
+	for i := 0; i < 5000; i++ {
+		var value int
+		var ok bool
+		switch random.Intn(6) {
+		case 0:
+			d, value, ok = d.PopFront()
+			if len(expected) > 0 {
+				if !ok || value != expected[0] {
+					t.Error(fmt.Sprintf("PopFront mismatch: expected=%d actual=%d ok=%v", expected[0], value, ok))
+				}
+				expected = expected[1:]
+			}
+		case 1:
+			d, value, ok = d.PopBack()
+			if len(expected) > 0 {
+				if !ok || value != expected[len(expected)-1] {
+					t.Error(fmt.Sprintf("PopBack mismatch: expected=%d actual=%d ok=%v", expected[len(expected)-1], value, ok))
+				}
+				expected = expected[:len(expected)-1]
+			}
+		case 2, 3:
+			d = d.PushFront(i)
+			expected = append([]int{i}, expected...)
+		default:
+			d = d.PushBack(i)
+			expected = append(expected, i)
+		}
+		if len(expected) == 0 && ok {
+			t.Error("pop returned true for empty deque")
+		}
+		if len(expected) > 0 {
+			front, _ := d.PeekFront()
+			back, _ := d.PeekBack()
+			if front != expected[0] || back != expected[len(expected)-1] {
+				t.Error(fmt.Sprintf("peek mismatch: expected=%d,%d actual=%d,%d", expected[0], expected[len(expected)-1], front, back))
+			}
+		}
+		if i%500 == 0 {
+			d.checkInvariants(createReporter(t))
+			if !slices.Equal(d.ToSlice(), expected) {
+				t.Error(fmt.Sprintf("ToSlice mismatch at %d", i))
+			}
+		}
+	}
+	d.checkInvariants(createReporter(t))
+	if d.Size() != len(expected) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(expected), d.Size()))
+	}
+	verifyRangedTwice(t, d.All(), expected)
+
+	equals := func(a int, b int) bool { return a == b }
+	other := CreateDeque[int]()
+	for _, value := range expected {
+		other = other.PushBack(value)
+	}
+	if !d.Equals(other, equals) || d.Equals(other.PushFront(1), equals) {
+		t.Error("Equals returned incorrect result")
+	}
+}
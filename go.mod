@@ -1 +1,3 @@
 module immutableMap
+
+go 1.24
@@ -0,0 +1,215 @@
+package immutableMap
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+)
+
+// LinkedMap is an immutable map that remembers the order in which its keys were
+// added.  Iteration visits keys in that order.  Assigning a new value to a key
+// already in the map does not change its position.
+type LinkedMap[K any, V any] interface {
+	Assign(key K, value V) LinkedMap[K, V]
+	Get(key K) V
+	Lookup(key K) (V, bool)
+	ContainsKey(key K) bool
+	Delete(key K) LinkedMap[K, V]
+	MoveToEnd(key K) LinkedMap[K, V]
+	Size() int
+	Iterate() MapIterator[K, V]
+	ForEach(v MapVisitor[K, V])
+	All() iter.Seq2[K, V]
+	First() (K, V, bool)
+	Last() (K, V, bool)
+	checkInvariants(report reporter)
+}
+
+// linkedValue is the value stored in the trie of a linkedMapImpl.  sequence is the
+// key's position in the order tree.
+type linkedValue[V any] struct {
+	sequence int64
+	value    V
+}
+
+// linkedMapImpl finds values using a trie and orders its keys using a tree that
+// maps the sequence number assigned to each key when it was added back to the key.
+// nextSequence is greater than every sequence number in the tree.
+type linkedMapImpl[K any, V any] struct {
+	hash         HashFunc[K]
+	equals       EqualsFunc[K]
+	root         *node[K, linkedValue[V]]
+	order        *treeNode[int64, K]
+	nextSequence int64
+	size         int
+}
+
+type linkedMapIteratorImpl[K any, V any] struct {
+	linkedMap *linkedMapImpl[K, V]
+	iterator  *treeIterator[int64, K]
+	key       K
+	value     V
+}
+
+// NewLinkedMap creates an empty LinkedMap whose keys are hashed and compared using
+// the provided functions.
+func NewLinkedMap[K any, V any](hash HashFunc[K], equals EqualsFunc[K]) LinkedMap[K, V] {
+	return &linkedMapImpl[K, V]{hash: hash, equals: equals, root: emptyNode[K, linkedValue[V]]()}
+}
+
+// NewComparableLinkedMap creates an empty LinkedMap for keys that can be compared
+// using ==.
+func NewComparableLinkedMap[K comparable, V any]() LinkedMap[K, V] {
+	return NewLinkedMap[K, V](comparableHash[K](), comparableEquals[K])
+}
+
+func (this *linkedMapImpl[K, V]) Assign(key K, value V) LinkedMap[K, V] {
+	sequence := this.nextSequence
+	newRoot, delta := this.root.update(this.hash(key), 0, key, func(oldValue linkedValue[V], present bool) (linkedValue[V], bool) {
+		if present {
+			sequence = oldValue.sequence
+		}
+		return linkedValue[V]{sequence: sequence, value: value}, true
+	}, nil, this.equals)
+	if newRoot == this.root {
+		return this
+	}
+	newMap := *this
+	newMap.root = newRoot
+	if delta > 0 {
+		newOrder, _ := this.order.assign(sequence, key, cmp.Compare[int64])
+		newMap.order = newOrder.blacken()
+		newMap.nextSequence++
+		newMap.size++
+	}
+	return &newMap
+}
+
+func (this *linkedMapImpl[K, V]) Get(key K) V {
+	value, _ := this.Lookup(key)
+	return value
+}
+
+func (this *linkedMapImpl[K, V]) Lookup(key K) (V, bool) {
+	linked, ok := this.root.get(this.hash(key), 0, key, this.equals)
+	return linked.value, ok
+}
+
+func (this *linkedMapImpl[K, V]) ContainsKey(key K) bool {
+	return this.root.contains(this.hash(key), 0, key, this.equals)
+}
+
+func (this *linkedMapImpl[K, V]) Delete(key K) LinkedMap[K, V] {
+	hashCode := this.hash(key)
+	linked, ok := this.root.get(hashCode, 0, key, this.equals)
+	if !ok {
+		return this
+	}
+	newRoot, _ := this.root.delete(hashCode, 0, key, this.equals)
+	if newRoot == nil {
+		newRoot = emptyNode[K, linkedValue[V]]()
+	}
+	newMap := *this
+	newMap.root = newRoot
+	newMap.order = this.order.remove(linked.sequence, cmp.Compare[int64]).blacken()
+	newMap.size--
+	return &newMap
+}
+
+// MoveToEnd returns a map in which key is the last key in iteration order.  The
+// receiver is returned if key is not in the map or is already the last key.
+func (this *linkedMapImpl[K, V]) MoveToEnd(key K) LinkedMap[K, V] {
+	hashCode := this.hash(key)
+	linked, ok := this.root.get(hashCode, 0, key, this.equals)
+	if !ok || linked.sequence == this.order.max().key {
+		return this
+	}
+	newMap := *this
+	newMap.root, _ = this.root.assign(hashCode, 0, key, linkedValue[V]{sequence: this.nextSequence, value: linked.value}, 0, this.equals)
+	newOrder, _ := this.order.remove(linked.sequence, cmp.Compare[int64]).blacken().assign(this.nextSequence, key, cmp.Compare[int64])
+	newMap.order = newOrder.blacken()
+	newMap.nextSequence++
+	return &newMap
+}
+
+func (this *linkedMapImpl[K, V]) Size() int {
+	return this.size
+}
+
+func (this *linkedMapImpl[K, V]) Iterate() MapIterator[K, V] {
+	return &linkedMapIteratorImpl[K, V]{linkedMap: this, iterator: this.order.createIterator(cmp.Compare[int64], 0, false, false)}
+}
+
+func (this *linkedMapImpl[K, V]) ForEach(v MapVisitor[K, V]) {
+	for i := this.Iterate(); i.Next(); {
+		v(i.Get())
+	}
+}
+
+// All returns an iterator over the keys and values of the map in the order the keys
+// were added for use with range.
+func (this *linkedMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+// First returns the earliest added key and its value.  The final result is false if
+// the map is empty.
+func (this *linkedMapImpl[K, V]) First() (K, V, bool) {
+	return this.entryFor(this.order.min())
+}
+
+// Last returns the most recently added key and its value.  The final result is
+// false if the map is empty.
+func (this *linkedMapImpl[K, V]) Last() (K, V, bool) {
+	return this.entryFor(this.order.max())
+}
+
+func (this *linkedMapImpl[K, V]) entryFor(n *treeNode[int64, K]) (K, V, bool) {
+	if n == nil {
+		var key K
+		var value V
+		return key, value, false
+	}
+	return n.value, this.Get(n.value), true
+}
+
+func (this *linkedMapIteratorImpl[K, V]) Next() bool {
+	if !this.iterator.Next() {
+		return false
+	}
+	_, this.key = this.iterator.Get()
+	this.value = this.linkedMap.Get(this.key)
+	return true
+}
+
+func (this *linkedMapIteratorImpl[K, V]) Get() (K, V) {
+	return this.key, this.value
+}
+
+func (this *linkedMapImpl[K, V]) checkInvariants(report reporter) {
+	this.root.checkInvariants(this.hash, this.equals, 0, report)
+	if isRed(this.order) {
+		report("red root detected")
+	}
+	this.order.checkInvariants(cmp.Compare[int64], report)
+	this.order.forEach(func(sequence int64, key K) {
+		if linked, ok := this.root.get(this.hash(key), 0, key, this.equals); !ok || linked.sequence != sequence {
+			report(fmt.Sprintf("order entry does not match trie: key=%v sequence=%d actual=%d found=%v", key, sequence, linked.sequence, ok))
+		}
+		if sequence >= this.nextSequence {
+			report(fmt.Sprintf("sequence not less than next sequence: key=%v sequence=%d next=%d", key, sequence, this.nextSequence))
+		}
+	})
+	if count := this.root.count(); count != this.size {
+		report(fmt.Sprintf("Size() does not match number of keys in trie: expected=%d actual=%d", this.size, count))
+	}
+	if count := treeSize(this.order); count != this.size {
+		report(fmt.Sprintf("Size() does not match number of keys in order: expected=%d actual=%d", this.size, count))
+	}
+}
@@ -0,0 +1,91 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func linkedMapString(m LinkedMap[string, int]) string {
+	answer := "|"
+	for key, value := range m.All() {
+		answer += fmt.Sprintf("%s=%d|", key, value)
+	}
+	return answer
+}
+
+func TestLinkedMap(t *testing.T) {
+	m := NewComparableLinkedMap[string, int]()
+	var expected []string
+	values := make(map[string]int)
+	random := rand.New(rand.NewSource(20))
+	for i := 0; i < 3000; i++ {
+		key := val(random.Intn(300))
+		switch random.Intn(4) {
+		case 0:
+			m = m.Delete(key)
+			if index := slices.Index(expected, key); index >= 0 {
+				expected = slices.Delete(expected, index, index+1)
+			}
+			delete(values, key)
+		case 1:
+			m = m.MoveToEnd(key)
+			if index := slices.Index(expected, key); index >= 0 {
+				expected = append(slices.Delete(expected, index, index+1), key)
+			}
+		default:
+			m = m.Assign(key, i)
+			if _, ok := values[key]; !ok {
+				expected = append(expected, key)
+			}
+			values[key] = i
+		}
+		if i%300 == 0 {
+			m.checkInvariants(createReporter(t))
+		}
+	}
+	m.checkInvariants(createReporter(t))
+
+	if m.Size() != len(expected) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(expected), m.Size()))
+	}
+	index := 0
+	for key, value := range m.All() {
+		if index >= len(expected) || key != expected[index] || value != values[key] {
+			t.Error(fmt.Sprintf("iteration mismatch at %d: key=%s value=%d", index, key, value))
+			break
+		}
+		index++
+	}
+}
+
+func TestLinkedMapOrder(t *testing.T) {
+	m := NewComparableLinkedMap[string, int]()
+	if _, _, ok := m.First(); ok {
+		t.Error("First returned true for empty map")
+	}
+	m = m.Assign("c", 1).Assign("a", 2).Assign("b", 3)
+	assertString(linkedMapString(m), "|c=1|a=2|b=3|", t)
+
+	updated := m.Assign("c", 10)
+	assertString(linkedMapString(updated), "|c=10|a=2|b=3|", t)
+	if m.Assign("a", 2) != m || m.MoveToEnd("b") != m || m.MoveToEnd("x") != m || m.Delete("x") != m {
+		t.Error("unchanged map was copied")
+	}
+
+	moved := m.MoveToEnd("c")
+	moved.checkInvariants(createReporter(t))
+	assertString(linkedMapString(moved), "|a=2|b=3|c=1|", t)
+	if key, value, ok := moved.First(); !ok || key != "a" || value != 2 {
+		t.Error(fmt.Sprintf("First mismatch: key=%s value=%d ok=%v", key, value, ok))
+	}
+	if key, value, ok := moved.Last(); !ok || key != "c" || value != 1 {
+		t.Error(fmt.Sprintf("Last mismatch: key=%s value=%d ok=%v", key, value, ok))
+	}
+
+	deleted := moved.Delete("a").Assign("a", 4)
+	deleted.checkInvariants(createReporter(t))
+	assertString(linkedMapString(deleted), "|b=3|c=1|a=4|", t)
+	assertString(linkedMapString(m), "|c=1|a=2|b=3|", t)
+}
@@ -1,102 +1,596 @@
 package immutableMap
 
-import "fmt"
+import (
+	"fmt"
+	"hash/maphash"
+	"iter"
+)
 
 type Object interface{}
 type HashCode uint32
-type HashFunc func(Object) HashCode
-type EqualsFunc func(Object, Object) bool
-type MapVisitor func(Object, Object)
+type HashFunc[K any] func(K) HashCode
+type EqualsFunc[K any] func(K, K) bool
+type MapVisitor[K any, V any] func(K, V)
+type MapPredicate[K any, V any] func(K, V) bool
 type reporter func(message string)
 
-type Map interface {
-	Assign(key Object, value Object) Map
-	Get(key Object) Object
-	Delete(key Object) Map
-	Keys() Set
+type Map[K any, V any] interface {
+	Assign(key K, value V) Map[K, V]
+	Get(key K) V
+	Lookup(key K) (V, bool)
+	ContainsKey(key K) bool
+	GetOrDefault(key K, defaultValue V) V
+	GetOrElse(key K, defaultFunc func() V) V
+	Delete(key K) Map[K, V]
+	AssignAll(entries []Entry[K, V]) Map[K, V]
+	DeleteAll(keys []K) Map[K, V]
+	Update(key K, updater func(oldValue V, present bool) (V, bool)) Map[K, V]
+	Upsert(key K, updater func(oldValue V, present bool) V) Map[K, V]
+	ComputeIfAbsent(key K, compute func(key K) V) Map[K, V]
+	ComputeIfPresent(key K, compute func(key K, oldValue V) (V, bool)) Map[K, V]
+	Merge(other Map[K, V], resolve func(key K, left V, right V) V) Map[K, V]
+	MergeWith(other Map[K, V], strategy MergeStrategy) Map[K, V]
+	Filter(p MapPredicate[K, V]) Map[K, V]
+	Partition(p MapPredicate[K, V]) (Map[K, V], Map[K, V])
+	MapValues(f func(key K, value V) V) Map[K, V]
+	Retain(keys Set[K]) Map[K, V]
+	Without(keys Set[K]) Map[K, V]
+	Keys() Set[K]
+	Values() Collection[V]
+	Entries() Collection[Entry[K, V]]
+	Equals(other Map[K, V], valueEquals EqualsFunc[V]) bool
+	HashCode(valueHash HashFunc[V]) HashCode
 	Size() int
-	Iterate() MapIterator
-	ForEach(v MapVisitor)
+	Iterate() MapIterator[K, V]
+	ForEach(v MapVisitor[K, V])
+	ForEachWhile(v MapPredicate[K, V]) bool
+	Find(p MapPredicate[K, V]) (K, V, bool)
+	Any(p MapPredicate[K, V]) bool
+	Every(p MapPredicate[K, V]) bool
+	All() iter.Seq2[K, V]
+	KeySeq() iter.Seq[K]
+	ValueSeq() iter.Seq[V]
+	ToBuilder() MapBuilder[K, V]
 	checkInvariants(report reporter)
 }
-type MapIterator interface {
+type MapIterator[K any, V any] interface {
 	Next() bool
-	Get() (Object, Object)
+	Get() (K, V)
 }
 
-type mapImpl struct {
-	hash   HashFunc
-	equals EqualsFunc
-	root   *node
-	size   int
+// MergeStrategy determines which value MergeWith keeps for a key found in both maps.
+type MergeStrategy int
+
+const (
+	// MergePreferLeft keeps the value from the receiver.
+	MergePreferLeft MergeStrategy = iota
+	// MergePreferRight keeps the value from the other map.
+	MergePreferRight
+	// MergeDropConflicts removes the key from the result.
+	MergeDropConflicts
+)
+
+// mapImpl stores its entries in a trie.  valueHash is nil unless the map was
+// created by NewMapWithValueHash, in which case the hash code of every value is
+// stored with its key so HashCode can be maintained by the trie.
+type mapImpl[K any, V any] struct {
+	hash      HashFunc[K]
+	equals    EqualsFunc[K]
+	valueHash HashFunc[V]
+	layout    *layoutToken
+	root      *node[K, V]
+	size      int
 }
 
-type mapIteratorImpl struct {
-	state *iteratorState
-	key   Object
-	value Object
+// layoutToken identifies the hash function used to place keys in a trie.  Every
+// collection derived from the same constructor call shares a token so that
+// operations combining two tries can tell whether their nodes line up.
+type layoutToken struct {
+	_ byte
 }
 
-func (this *mapImpl) withRoot(newRoot *node, delta int) *mapImpl {
+type mapIteratorImpl[K any, V any] struct {
+	state *iteratorState[K, V]
+	key   K
+	value V
+}
+
+func newLayoutToken() *layoutToken {
+	return &layoutToken{}
+}
+
+func (this *mapImpl[K, V]) withRoot(newRoot *node[K, V], delta int) *mapImpl[K, V] {
 	newMap := *this
 	newMap.root = newRoot
 	newMap.size += delta
 	return &newMap
 }
 
-func CreateMap(hash HashFunc, equals EqualsFunc) Map {
-	return &mapImpl{hash: hash, equals: equals, root: emptyNode()}
+// CreateMap creates an empty Map using Object keys and values.  It is retained for
+// compatibility with code written before Map accepted type parameters.
+func CreateMap(hash HashFunc[Object], equals EqualsFunc[Object]) Map[Object, Object] {
+	return NewMap[Object, Object](hash, equals)
+}
+
+// NewMap creates an empty Map whose keys are hashed and compared using the
+// provided functions.
+func NewMap[K any, V any](hash HashFunc[K], equals EqualsFunc[K]) Map[K, V] {
+	return &mapImpl[K, V]{hash: hash, equals: equals, layout: newLayoutToken(), root: emptyNode[K, V]()}
+}
+
+// NewMapWithValueHash creates an empty Map whose keys are hashed and compared using
+// the provided functions and whose values are hashed using valueHash.  The trie
+// maintains the hash code of every entry so HashCode takes constant time and Equals
+// can reject maps whose values differ without comparing them, provided valueHash is
+// consistent with the valueEquals passed to Equals.
+func NewMapWithValueHash[K any, V any](hash HashFunc[K], equals EqualsFunc[K], valueHash HashFunc[V]) Map[K, V] {
+	return &mapImpl[K, V]{hash: hash, equals: equals, valueHash: valueHash, layout: newLayoutToken(), root: emptyNode[K, V]()}
+}
+
+// NewComparableMap creates an empty Map for keys that can be compared using ==.
+// Keys are hashed using hash/maphash with a seed chosen randomly when the program
+// starts.
+func NewComparableMap[K comparable, V any]() Map[K, V] {
+	return NewMap[K, V](comparableHash[K](), comparableEquals[K])
+}
+
+// comparableSeed is shared by every comparable collection so that equal collections
+// created by separate constructor calls have the same HashCode.
+var comparableSeed = maphash.MakeSeed()
+
+func comparableHash[K comparable]() HashFunc[K] {
+	return func(key K) HashCode {
+		h := maphash.Comparable(comparableSeed, key)
+		return HashCode(h ^ (h >> 32))
+	}
+}
+
+func comparableEquals[K comparable](a K, b K) bool {
+	return a == b
+}
+
+// MapHashFunc returns a HashFunc that computes the HashCode of a Map using
+// valueHash for its values.  Together with MapEqualsFunc it allows maps to be used
+// as keys of other maps.
+func MapHashFunc[K any, V any](valueHash HashFunc[V]) HashFunc[Map[K, V]] {
+	return func(m Map[K, V]) HashCode {
+		return m.HashCode(valueHash)
+	}
+}
+
+// MapEqualsFunc returns an EqualsFunc that compares maps using valueEquals for
+// their values.
+func MapEqualsFunc[K any, V any](valueEquals EqualsFunc[V]) EqualsFunc[Map[K, V]] {
+	return func(a Map[K, V], b Map[K, V]) bool {
+		return a.Equals(b, valueEquals)
+	}
+}
+
+// ObjectHashFunc adapts a typed HashFunc for use with CreateMap or CreateSet.
+func ObjectHashFunc[K any](hash HashFunc[K]) HashFunc[Object] {
+	return func(key Object) HashCode {
+		return hash(key.(K))
+	}
+}
+
+// ObjectEqualsFunc adapts a typed EqualsFunc for use with CreateMap or CreateSet.
+func ObjectEqualsFunc[K any](equals EqualsFunc[K]) EqualsFunc[Object] {
+	return func(a Object, b Object) bool {
+		return equals(a.(K), b.(K))
+	}
+}
+
+// FoldMap combines every entry of m into a single value by calling f with the
+// result of the previous call, starting with initial.
+func FoldMap[K any, V any, A any](m Map[K, V], initial A, f func(acc A, key K, value V) A) A {
+	answer := initial
+	m.ForEach(func(key K, value V) {
+		answer = f(answer, key, value)
+	})
+	return answer
+}
+
+func (this *mapImpl[K, V]) Assign(key K, value V) Map[K, V] {
+	newRoot, delta := this.root.assign(this.hash(key), 0, key, value, hashValue(this.valueHash, value), this.equals)
+	return this.withRoot(newRoot, delta)
+}
+
+// Get returns the value assigned to key or the zero value if key is not in the map.
+// Use Lookup to distinguish a missing key from one assigned the zero value.
+func (this *mapImpl[K, V]) Get(key K) V {
+	value, _ := this.Lookup(key)
+	return value
+}
+
+// Lookup returns the value assigned to key and true, or the zero value and false
+// if key is not in the map.
+func (this *mapImpl[K, V]) Lookup(key K) (V, bool) {
+	return this.root.get(this.hash(key), 0, key, this.equals)
+}
+
+func (this *mapImpl[K, V]) ContainsKey(key K) bool {
+	return this.root.contains(this.hash(key), 0, key, this.equals)
+}
+
+// GetOrDefault returns the value assigned to key or defaultValue if key is not in the map.
+func (this *mapImpl[K, V]) GetOrDefault(key K, defaultValue V) V {
+	if value, ok := this.Lookup(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetOrElse returns the value assigned to key or the result of calling defaultFunc
+// if key is not in the map.  defaultFunc is only called when the key is missing.
+func (this *mapImpl[K, V]) GetOrElse(key K, defaultFunc func() V) V {
+	if value, ok := this.Lookup(key); ok {
+		return value
+	}
+	return defaultFunc()
 }
 
-func (this *mapImpl) Assign(key Object, value Object) Map {
-	newRoot, delta := this.root.assign(this.hash(key), key, value, this.equals)
+func (this *mapImpl[K, V]) Delete(key K) Map[K, V] {
+	newRoot, delta := this.root.delete(this.hash(key), 0, key, this.equals)
+	if newRoot == nil {
+		newRoot = emptyNode[K, V]()
+	}
 	return this.withRoot(newRoot, delta)
 }
 
-func (this *mapImpl) Get(key Object) Object {
-	return this.root.get(this.hash(key), key, this.equals)
+// AssignAll assigns every entry in order so later entries replace earlier ones with
+// the same key.  The changes are grouped by hash so that each node of the trie is
+// copied at most once.  The receiver is returned if nothing changed.
+func (this *mapImpl[K, V]) AssignAll(entries []Entry[K, V]) Map[K, V] {
+	batch := make([]batchEntry[K, V], len(entries))
+	for i, entry := range entries {
+		batch[i] = batchEntry[K, V]{hash: this.hash(entry.Key), valueHash: hashValue(this.valueHash, entry.Value), key: entry.Key, value: entry.Value}
+	}
+	return this.withFilteredRoot(this.root.applyBatch(batch, 0, this.equals))
+}
+
+// DeleteAll removes every key in keys.  The changes are grouped by hash so that
+// each node of the trie is copied at most once.  The receiver is returned if
+// nothing changed.
+func (this *mapImpl[K, V]) DeleteAll(keys []K) Map[K, V] {
+	batch := make([]batchEntry[K, V], len(keys))
+	for i, key := range keys {
+		batch[i] = batchEntry[K, V]{hash: this.hash(key), key: key, delete: true}
+	}
+	return this.withFilteredRoot(this.root.applyBatch(batch, 0, this.equals))
 }
 
-func (this *mapImpl) Delete(key Object) Map {
-	newRoot, delta := this.root.delete(this.hash(key), key, this.equals)
+// Update calls updater with the current value for key (or the zero value and false
+// if key is not in the map).  If updater returns true the key is assigned the
+// returned value, otherwise the key is removed.  The map is searched only once and
+// the receiver is returned if the result leaves the map unchanged.  Returning the
+// current value leaves the map unchanged even if it is a slice, map or struct that
+// cannot be compared using ==.
+func (this *mapImpl[K, V]) Update(key K, updater func(oldValue V, present bool) (V, bool)) Map[K, V] {
+	newRoot, delta := this.root.update(this.hash(key), 0, key, updater, this.valueHash, this.equals)
+	if newRoot == this.root {
+		return this
+	}
 	if newRoot == nil {
-		newRoot = emptyNode()
+		newRoot = emptyNode[K, V]()
 	}
 	return this.withRoot(newRoot, delta)
 }
 
-func (this *mapImpl) Keys() Set {
+// Upsert assigns key the value returned by updater, which is passed the current
+// value for key (or the zero value and false if key is not in the map).
+func (this *mapImpl[K, V]) Upsert(key K, updater func(oldValue V, present bool) V) Map[K, V] {
+	return this.Update(key, func(oldValue V, present bool) (V, bool) {
+		return updater(oldValue, present), true
+	})
+}
+
+// ComputeIfAbsent assigns key the value returned by compute if key is not already
+// in the map.  compute is not called if the key is present.
+func (this *mapImpl[K, V]) ComputeIfAbsent(key K, compute func(key K) V) Map[K, V] {
+	return this.Update(key, func(oldValue V, present bool) (V, bool) {
+		if present {
+			return oldValue, true
+		}
+		return compute(key), true
+	})
+}
+
+// ComputeIfPresent replaces the value for key with the value returned by compute if
+// key is in the map.  If compute returns false the key is removed.  compute is not
+// called if the key is not present.
+func (this *mapImpl[K, V]) ComputeIfPresent(key K, compute func(key K, oldValue V) (V, bool)) Map[K, V] {
+	return this.Update(key, func(oldValue V, present bool) (V, bool) {
+		if !present {
+			return oldValue, false
+		}
+		return compute(key, oldValue)
+	})
+}
+
+// Merge returns a map containing the entries of both maps.  For keys found in both
+// maps resolve is called with the value from this map (left) and the value from
+// other (right) and the key is assigned the result.  When both maps were derived
+// from the same original map the tries are merged node by node and subtrees found
+// in only one of them are shared with the result.
+func (this *mapImpl[K, V]) Merge(other Map[K, V], resolve func(key K, left V, right V) V) Map[K, V] {
+	return this.merge(other, &nodeMerger[K, V]{
+		equals: this.equals,
+		resolve: func(key K, left V, right V) (V, bool) {
+			return resolve(key, left, right), true
+		},
+		valueHash:   this.valueHash,
+		sharedNodes: resolveSharedNodes,
+	})
+}
+
+// MergeWith returns a map containing the entries of both maps using strategy to
+// choose the value for keys found in both maps.
+func (this *mapImpl[K, V]) MergeWith(other Map[K, V], strategy MergeStrategy) Map[K, V] {
+	merger := &nodeMerger[K, V]{equals: this.equals, valueHash: this.valueHash}
+	switch strategy {
+	case MergePreferLeft:
+		merger.sharedNodes = keepSharedNodes
+	case MergePreferRight:
+		merger.resolve = func(key K, left V, right V) (V, bool) {
+			return right, true
+		}
+		merger.sharedNodes = keepSharedNodes
+	case MergeDropConflicts:
+		merger.resolve = func(key K, left V, right V) (V, bool) {
+			return left, false
+		}
+		merger.sharedNodes = dropSharedNodes
+	default:
+		panic(fmt.Sprintf("unknown merge strategy: %d", strategy))
+	}
+	return this.merge(other, merger)
+}
+
+func (this *mapImpl[K, V]) merge(other Map[K, V], merger *nodeMerger[K, V]) Map[K, V] {
+	if otherImpl, ok := other.(*mapImpl[K, V]); ok && otherImpl.layout == this.layout {
+		return this.withFilteredRoot(merger.merge(this.root, otherImpl.root))
+	}
+
+	builder := this.ToBuilder()
+	other.ForEach(func(key K, right V) {
+		if left, present := builder.Lookup(key); !present {
+			builder.Assign(key, right)
+		} else if merger.resolve == nil {
+			return
+		} else if value, keep := merger.resolve(key, left, right); keep {
+			builder.Assign(key, value)
+		} else {
+			builder.Delete(key)
+		}
+	})
+	return builder.Build()
+}
+
+// Filter returns a map containing only the entries for which p returns true.
+// Subtrees of the trie in which every entry matches are shared with the result.
+func (this *mapImpl[K, V]) Filter(p MapPredicate[K, V]) Map[K, V] {
+	return this.withFilteredRoot(this.root.filter(p))
+}
+
+// Partition returns a map containing the entries for which p returns true and a
+// map containing the rest.  p is called once for each entry.
+func (this *mapImpl[K, V]) Partition(p MapPredicate[K, V]) (Map[K, V], Map[K, V]) {
+	matching, other, otherCount := this.root.partition(p)
+	return this.withFilteredRoot(matching, -otherCount), this.withFilteredRoot(other, otherCount-this.size)
+}
+
+// MapValues returns a map with the same keys in which every value is replaced by
+// the result of calling f.  The receiver is returned if no value changed.
+func (this *mapImpl[K, V]) MapValues(f func(key K, value V) V) Map[K, V] {
+	return this.withFilteredRoot(this.root.mapValues(func(key K, value V) (V, bool) {
+		return f(key, value), true
+	}, this.valueHash))
+}
+
+// Retain returns a map containing only the entries whose keys are in keys.
+func (this *mapImpl[K, V]) Retain(keys Set[K]) Map[K, V] {
+	if other, ok := keys.(*setImpl[K, V]); ok && other.layout == this.layout {
+		merger := &nodeMerger[K, V]{equals: this.equals, sharedNodes: keepSharedNodes}
+		return this.withFilteredRoot(merger.intersect(this.root, other.root))
+	}
+	return this.Filter(func(key K, _ V) bool {
+		return keys.Contains(key)
+	})
+}
+
+// Without returns a map containing only the entries whose keys are not in keys.
+func (this *mapImpl[K, V]) Without(keys Set[K]) Map[K, V] {
+	if other, ok := keys.(*setImpl[K, V]); ok && other.layout == this.layout {
+		return this.withFilteredRoot(this.root.difference(other.root, this.equals))
+	}
+	if keys.Size() < this.size/4 {
+		builder := this.ToBuilder()
+		keys.ForEach(func(key K) {
+			builder.Delete(key)
+		})
+		if builder.Size() == this.size {
+			return this
+		}
+		return builder.Build()
+	}
+	return this.Filter(func(key K, _ V) bool {
+		return !keys.Contains(key)
+	})
+}
+
+func (this *mapImpl[K, V]) withFilteredRoot(newRoot *node[K, V], delta int) Map[K, V] {
+	if newRoot == this.root {
+		return this
+	} else if newRoot == nil {
+		newRoot = emptyNode[K, V]()
+	}
+	return this.withRoot(newRoot, delta)
+}
+
+func (this *mapImpl[K, V]) Keys() Set[K] {
 	return keysSet(this)
 }
 
-func (this *mapImpl) Size() int {
+// Equals reports whether both maps contain the same keys with values considered
+// equal by valueEquals.  When both maps were derived from the same original map
+// subtrees whose entries differ are detected using their content hash without
+// comparing the entries themselves.  The content hash only covers values if the
+// map was created by NewMapWithValueHash.
+func (this *mapImpl[K, V]) Equals(other Map[K, V], valueEquals EqualsFunc[V]) bool {
+	if this.Size() != other.Size() {
+		return false
+	}
+	if otherImpl, ok := other.(*mapImpl[K, V]); ok && otherImpl.layout == this.layout {
+		return this.root.equalTo(otherImpl.root, this.equals, valueEquals)
+	}
+	for i := this.Iterate(); i.Next(); {
+		key, value := i.Get()
+		otherValue, present := other.Lookup(key)
+		if !present || !valueEquals(value, otherValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// HashCode returns a hash code computed from every key and value in the map.  The
+// result does not depend on iteration order so maps that are equal according to
+// Equals have the same hash code when valueHash is consistent with valueEquals.  If
+// the map was created by NewMapWithValueHash the hash code maintained by the trie
+// is returned in constant time and valueHash is not used.  Otherwise every value is
+// hashed using valueHash.
+func (this *mapImpl[K, V]) HashCode(valueHash HashFunc[V]) HashCode {
+	if this.valueHash != nil {
+		return this.root.entryContentHash
+	}
+	var answer HashCode
+	this.ForEach(func(key K, value V) {
+		answer += entryHash(this.hash(key), valueHash(value))
+	})
+	return answer
+}
+
+// Values returns a read-only view of the values in the map.  The view shares the
+// map's trie so creating it does not copy the values.
+func (this *mapImpl[K, V]) Values() Collection[V] {
+	return &nodeView[K, V, V]{root: this.root, size: this.size, project: projectValue[K, V]}
+}
+
+// Entries returns a read-only view of the keys and values in the map.  The view
+// shares the map's trie so creating it does not copy the entries.
+func (this *mapImpl[K, V]) Entries() Collection[Entry[K, V]] {
+	return &nodeView[K, V, Entry[K, V]]{root: this.root, size: this.size, project: projectEntry[K, V]}
+}
+
+func (this *mapImpl[K, V]) Size() int {
 	return this.size
 }
 
-func (this *mapImpl) Iterate() MapIterator {
-	return &mapIteratorImpl{state: this.root.createIteratorState(nil)}
+func (this *mapImpl[K, V]) Iterate() MapIterator[K, V] {
+	return &mapIteratorImpl[K, V]{state: this.root.createIteratorState(nil)}
 }
 
-func (this *mapImpl) ForEach(v MapVisitor) {
+func (this *mapImpl[K, V]) ForEach(v MapVisitor[K, V]) {
 	this.root.forEach(v)
 }
 
-func (this *mapImpl) checkInvariants(report reporter) {
+// ForEachWhile calls v for each key and value in the map until v returns false.
+// It returns true if every entry was visited.
+func (this *mapImpl[K, V]) ForEachWhile(v MapPredicate[K, V]) bool {
+	return this.root.forEachWhile(v)
+}
+
+// Find returns the first key and value for which p returns true.  The final result
+// is false if no entry matches.
+func (this *mapImpl[K, V]) Find(p MapPredicate[K, V]) (K, V, bool) {
+	var foundKey K
+	var foundValue V
+	found := !this.root.forEachWhile(func(key K, value V) bool {
+		if p(key, value) {
+			foundKey, foundValue = key, value
+			return false
+		}
+		return true
+	})
+	return foundKey, foundValue, found
+}
+
+// Any reports whether p returns true for at least one entry in the map.
+func (this *mapImpl[K, V]) Any(p MapPredicate[K, V]) bool {
+	_, _, found := this.Find(p)
+	return found
+}
+
+// Every reports whether p returns true for every entry in the map.  It returns
+// true for an empty map.
+func (this *mapImpl[K, V]) Every(p MapPredicate[K, V]) bool {
+	return this.root.forEachWhile(p)
+}
+
+// All returns an iterator over the keys and values of the map for use with range.
+func (this *mapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+// KeySeq returns an iterator over the keys of the map for use with range.  Use
+// Keys to obtain the keys as a Set.
+func (this *mapImpl[K, V]) KeySeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if key, _ := i.Get(); !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// ValueSeq returns an iterator over the values of the map for use with range.
+func (this *mapImpl[K, V]) ValueSeq() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if _, value := i.Get(); !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+func (this *mapImpl[K, V]) checkInvariants(report reporter) {
 	this.root.checkInvariants(this.hash, this.equals, 0, report)
 	size := 0
 	for i := this.Iterate(); i.Next(); {
 		key, expected := i.Get()
-		actual := this.Get(key)
-		if expected != actual {
+		actual, ok := this.Lookup(key)
+		if !ok || !sameValue(expected, actual) {
 			report(fmt.Sprintf("Get returned incorrect result: key=%v expected=%v actual=%v", key, expected, actual))
 		}
+		if !this.ContainsKey(key) {
+			report(fmt.Sprintf("ContainsKey returned false for key from iterator: key=%v", key))
+		}
 		size++
 	}
 	if this.size != size {
 		report(fmt.Sprintf("Size() does not match number of keys in iterator: expected=%d actual=%d", this.size, size))
 	}
+	var hashCode, entryHashCode HashCode
+	this.ForEach(func(key K, value V) {
+		hashCode += elementHash(this.hash(key))
+		entryHashCode += entryHash(this.hash(key), hashValue(this.valueHash, value))
+	})
+	if hashCode != this.root.contentHash {
+		report(fmt.Sprintf("content hash does not match hash of keys: expected=%d actual=%d", hashCode, this.root.contentHash))
+	}
+	if entryHashCode != this.root.entryContentHash {
+		report(fmt.Sprintf("entry content hash does not match hash of entries: expected=%d actual=%d", entryHashCode, this.root.entryContentHash))
+	}
 	i2 := this.Iterate()
-	this.ForEach(func(key Object, value Object) {
+	this.ForEach(func(key K, value V) {
 		if !i2.Next() {
 			report(fmt.Sprintf("Next() returned false in ForEach"))
 		}
@@ -107,7 +601,7 @@ func (this *mapImpl) checkInvariants(report reporter) {
 	})
 }
 
-func (this *mapIteratorImpl) Next() bool {
+func (this *mapIteratorImpl[K, V]) Next() bool {
 	if this.state == nil {
 		return false
 	} else {
@@ -116,6 +610,6 @@ func (this *mapIteratorImpl) Next() bool {
 	}
 }
 
-func (this *mapIteratorImpl) Get() (Object, Object) {
+func (this *mapIteratorImpl[K, V]) Get() (K, V) {
 	return this.key, this.value
 }
@@ -0,0 +1,170 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Multimap is an immutable map from each key to a non-empty Set of values.  A key
+// is removed as soon as its last value is removed.  Iteration visits each key and
+// value pair.
+type Multimap[K any, V any] interface {
+	Put(key K, value V) Multimap[K, V]
+	Remove(key K, value V) Multimap[K, V]
+	RemoveAll(key K) Multimap[K, V]
+	Get(key K) Set[V]
+	ContainsKey(key K) bool
+	ContainsEntry(key K, value V) bool
+	Keys() Set[K]
+	KeyCount() int
+	EntryCount() int
+	Iterate() MapIterator[K, V]
+	ForEach(v MapVisitor[K, V])
+	All() iter.Seq2[K, V]
+	checkInvariants(report reporter)
+}
+
+// multimapImpl stores a Set of values for each key.  Every Set is derived from
+// emptySet so they all hash and compare values the same way.  entryCount is the
+// sum of the sizes of the sets.
+type multimapImpl[K any, V any] struct {
+	entries    Map[K, Set[V]]
+	emptySet   Set[V]
+	entryCount int
+}
+
+type multimapIteratorImpl[K any, V any] struct {
+	keys   MapIterator[K, Set[V]]
+	values SetIterator[V]
+	key    K
+	value  V
+}
+
+// NewMultimap creates an empty Multimap whose keys and values are hashed and
+// compared using the provided functions.
+func NewMultimap[K any, V any](keyHash HashFunc[K], keyEquals EqualsFunc[K], valueHash HashFunc[V], valueEquals EqualsFunc[V]) Multimap[K, V] {
+	return &multimapImpl[K, V]{entries: NewMap[K, Set[V]](keyHash, keyEquals), emptySet: NewSet[V](valueHash, valueEquals)}
+}
+
+// NewComparableMultimap creates an empty Multimap for keys and values that can be
+// compared using ==.
+func NewComparableMultimap[K comparable, V comparable]() Multimap[K, V] {
+	return &multimapImpl[K, V]{entries: NewComparableMap[K, Set[V]](), emptySet: NewComparableSet[V]()}
+}
+
+func (this *multimapImpl[K, V]) withSet(key K, oldSet Set[V], newSet Set[V]) Multimap[K, V] {
+	delta := newSet.Size() - oldSet.Size()
+	if delta == 0 {
+		return this
+	}
+	newMap := *this
+	if newSet.Size() == 0 {
+		newMap.entries = this.entries.Delete(key)
+	} else {
+		newMap.entries = this.entries.Assign(key, newSet)
+	}
+	newMap.entryCount += delta
+	return &newMap
+}
+
+// Put adds value to the set of values for key.  The receiver is returned if the
+// value was already present.
+func (this *multimapImpl[K, V]) Put(key K, value V) Multimap[K, V] {
+	oldSet := this.Get(key)
+	return this.withSet(key, oldSet, oldSet.Add(value))
+}
+
+// Remove removes value from the set of values for key and removes key if no values
+// remain.  The receiver is returned if the value was not present.
+func (this *multimapImpl[K, V]) Remove(key K, value V) Multimap[K, V] {
+	oldSet := this.Get(key)
+	return this.withSet(key, oldSet, oldSet.Delete(value))
+}
+
+// RemoveAll removes key and all of its values.
+func (this *multimapImpl[K, V]) RemoveAll(key K) Multimap[K, V] {
+	return this.withSet(key, this.Get(key), this.emptySet)
+}
+
+// Get returns the set of values for key or an empty set if key is not present.
+func (this *multimapImpl[K, V]) Get(key K) Set[V] {
+	return this.entries.GetOrDefault(key, this.emptySet)
+}
+
+func (this *multimapImpl[K, V]) ContainsKey(key K) bool {
+	return this.entries.ContainsKey(key)
+}
+
+func (this *multimapImpl[K, V]) ContainsEntry(key K, value V) bool {
+	return this.Get(key).Contains(value)
+}
+
+func (this *multimapImpl[K, V]) Keys() Set[K] {
+	return this.entries.Keys()
+}
+
+// KeyCount returns the number of distinct keys.
+func (this *multimapImpl[K, V]) KeyCount() int {
+	return this.entries.Size()
+}
+
+// EntryCount returns the number of key and value pairs.
+func (this *multimapImpl[K, V]) EntryCount() int {
+	return this.entryCount
+}
+
+func (this *multimapImpl[K, V]) Iterate() MapIterator[K, V] {
+	return &multimapIteratorImpl[K, V]{keys: this.entries.Iterate()}
+}
+
+func (this *multimapImpl[K, V]) ForEach(v MapVisitor[K, V]) {
+	this.entries.ForEach(func(key K, values Set[V]) {
+		values.ForEach(func(value V) {
+			v(key, value)
+		})
+	})
+}
+
+// All returns an iterator over every key and value pair for use with range.
+func (this *multimapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *multimapIteratorImpl[K, V]) Next() bool {
+	for this.values == nil || !this.values.Next() {
+		if !this.keys.Next() {
+			this.values = nil
+			return false
+		}
+		var values Set[V]
+		this.key, values = this.keys.Get()
+		this.values = values.Iterate()
+	}
+	this.value = this.values.Get()
+	return true
+}
+
+func (this *multimapIteratorImpl[K, V]) Get() (K, V) {
+	return this.key, this.value
+}
+
+func (this *multimapImpl[K, V]) checkInvariants(report reporter) {
+	this.entries.checkInvariants(report)
+	count := 0
+	this.entries.ForEach(func(key K, values Set[V]) {
+		values.checkInvariants(report)
+		if values.Size() == 0 {
+			report(fmt.Sprintf("empty value set detected: key=%v", key))
+		}
+		count += values.Size()
+	})
+	if count != this.entryCount {
+		report(fmt.Sprintf("EntryCount() does not match number of values: expected=%d actual=%d", this.entryCount, count))
+	}
+}
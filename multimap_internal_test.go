@@ -0,0 +1,79 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestMultimap(t *testing.T) {
+	m := NewComparableMultimap[int, string]()
+	expected := make(map[int]map[string]bool)
+	random := rand.New(rand.NewSource(23))
+	for i := 0; i < 5000; i++ {
+		key := random.Intn(50)
+		value := val(random.Intn(20))
+		switch random.Intn(6) {
+		case 0:
+			m = m.Remove(key, value)
+			delete(expected[key], value)
+			if len(expected[key]) == 0 {
+				delete(expected, key)
+			}
+		case 1:
+			m = m.RemoveAll(key)
+			delete(expected, key)
+		default:
+			m = m.Put(key, value)
+			if expected[key] == nil {
+				expected[key] = make(map[string]bool)
+			}
+			expected[key][value] = true
+		}
+		if i%500 == 0 {
+			m.checkInvariants(createReporter(t))
+		}
+	}
+	m.checkInvariants(createReporter(t))
+
+	count := 0
+	for key, values := range expected {
+		count += len(values)
+		if m.Get(key).Size() != len(values) {
+			t.Error(fmt.Sprintf("Get size mismatch: key=%d expected=%d actual=%d", key, len(values), m.Get(key).Size()))
+		}
+		for value := range values {
+			if !m.ContainsEntry(key, value) {
+				t.Error(fmt.Sprintf("ContainsEntry returned false: key=%d value=%s", key, value))
+			}
+		}
+	}
+	if m.KeyCount() != len(expected) || m.EntryCount() != count {
+		t.Error(fmt.Sprintf("count mismatch: expected=%d,%d actual=%d,%d", len(expected), count, m.KeyCount(), m.EntryCount()))
+	}
+	visited := 0
+	for key, value := range m.All() {
+		if !expected[key][value] {
+			t.Error(fmt.Sprintf("unexpected entry: key=%d value=%s", key, value))
+		}
+		visited++
+	}
+	if visited != count {
+		t.Error(fmt.Sprintf("iteration count mismatch: expected=%d actual=%d", count, visited))
+	}
+}
+
+func TestMultimapEmptyKeys(t *testing.T) {
+	m := NewComparableMultimap[string, int]().Put("a", 1).Put("a", 2).Put("b", 3)
+	if m.Put("a", 1) != m || m.Remove("a", 5) != m || m.Remove("c", 1) != m || m.RemoveAll("c") != m {
+		t.Error("unchanged multimap was copied")
+	}
+	removed := m.Remove("a", 1).Remove("a", 2)
+	removed.checkInvariants(createReporter(t))
+	if removed.ContainsKey("a") || removed.KeyCount() != 1 || removed.EntryCount() != 1 {
+		t.Error(fmt.Sprintf("key with no values was not removed: keys=%d entries=%d", removed.KeyCount(), removed.EntryCount()))
+	}
+	if removed.Get("a").Size() != 0 || m.Get("a").Size() != 2 {
+		t.Error("removing values modified the original multimap")
+	}
+}
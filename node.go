@@ -3,45 +3,107 @@ package immutableMap
 import (
 	"fmt"
 	"math/bits"
+	"reflect"
+	"slices"
 )
 
-type keyValueList struct {
-	next  *keyValueList
-	key   Object
-	value Object
+// keyValueList holds the keys of a node.  valueHash is the hash code of the value,
+// which is zero if the trie was built without a value hash function.
+type keyValueList[K any, V any] struct {
+	next      *keyValueList[K, V]
+	hash      HashCode
+	valueHash HashCode
+	key       K
+	value     V
 }
 
-type node struct {
-	keys     *keyValueList
+type node[K any, V any] struct {
+	keys     *keyValueList[K, V]
 	bitmask  uint32
-	children []*node
+	children []*node[K, V]
+	nodeTotals
+	edit *editToken
 }
 
-type iteratorState struct {
-	next         *iteratorState
-	currentNode  *node
+// nodeTotals summarizes the keys of a node and its descendants.  contentHash is the
+// sum of elementHash for the hash codes of every key and entryContentHash is the sum
+// of entryHash for the hash codes of every key and its value.  Neither depends on
+// the order of the keys so nodes with different contents can often be detected
+// without comparing their keys.  size is the number of keys.
+type nodeTotals struct {
+	contentHash      HashCode
+	entryContentHash HashCode
+	size             int
+}
+
+// editToken identifies the builder that owns a node.  Nodes owned by a builder's
+// current token may be modified in place by that builder.  Nodes created by the
+// persistent operations are never owned since their token is nil.
+type editToken struct {
+	_ byte
+}
+
+type iteratorState[K any, V any] struct {
+	next         *iteratorState[K, V]
+	currentNode  *node[K, V]
 	currentIndex int
-	currentKey   *keyValueList
+	currentKey   *keyValueList[K, V]
 }
 
-func (this *node) isEmpty() bool {
+func (this *node[K, V]) isEmpty() bool {
 	return this.keys == nil && this.bitmask == 0
 }
 
-func emptyNode() *node {
-	return &node{}
+func emptyNode[K any, V any]() *node[K, V] {
+	return &node[K, V]{}
 }
 
-func (this *node) assign(hashCode HashCode, key Object, value Object, equals EqualsFunc) (*node, int) {
-	if hashCode == 0 {
-		return this.setKeyAndValue(key, value, equals)
+// newNode creates a node with the given contents and computes its totals.
+func newNode[K any, V any](keys *keyValueList[K, V], bitmask uint32, children []*node[K, V]) *node[K, V] {
+	totals := keys.totals()
+	for _, child := range children {
+		totals = totals.plus(child.nodeTotals)
+	}
+	return &node[K, V]{keys: keys, bitmask: bitmask, children: children, nodeTotals: totals}
+}
+
+func (this nodeTotals) plus(other nodeTotals) nodeTotals {
+	return nodeTotals{
+		contentHash:      this.contentHash + other.contentHash,
+		entryContentHash: this.entryContentHash + other.entryContentHash,
+		size:             this.size + other.size,
+	}
+}
+
+func (this nodeTotals) minus(other nodeTotals) nodeTotals {
+	return nodeTotals{
+		contentHash:      this.contentHash - other.contentHash,
+		entryContentHash: this.entryContentHash - other.entryContentHash,
+		size:             this.size - other.size,
+	}
+}
+
+// hashValue returns the hash code of value or zero if valueHash is nil.
+func hashValue[V any](valueHash HashFunc[V], value V) HashCode {
+	if valueHash == nil {
+		return 0
+	}
+	return valueHash(value)
+}
+
+// assign adds the key and value to the trie.  hashCode is the full hash code of the
+// key and shift is the number of bits of it consumed by the ancestors of this node.
+// valueHash is the hash code of the value.
+func (this *node[K, V]) assign(hashCode HashCode, shift uint, key K, value V, valueHash HashCode, equals EqualsFunc[K]) (*node[K, V], int) {
+	if hashCode>>shift == 0 {
+		return this.setKeyAndValue(hashCode, key, value, valueHash, equals)
 	} else {
-		index := indexForHash(hashCode)
+		index := indexForHash(hashCode >> shift)
 		oldChild := this.getChild(index)
 		if oldChild == nil {
-			oldChild = emptyNode()
+			oldChild = emptyNode[K, V]()
 		}
-		newChild, delta := oldChild.assign(hashCode>>5, key, value, equals)
+		newChild, delta := oldChild.assign(hashCode, shift+5, key, value, valueHash, equals)
 		if newChild == oldChild {
 			return this, delta
 		} else {
@@ -50,40 +112,41 @@ func (this *node) assign(hashCode HashCode, key Object, value Object, equals Equ
 	}
 }
 
-func (this *node) get(hashCode HashCode, key Object, equals EqualsFunc) Object {
-	if hashCode == 0 {
+func (this *node[K, V]) get(hashCode HashCode, shift uint, key K, equals EqualsFunc[K]) (V, bool) {
+	if hashCode>>shift == 0 {
 		return this.getValueForKey(key, equals)
 	} else {
-		index := indexForHash(hashCode)
+		index := indexForHash(hashCode >> shift)
 		oldChild := this.getChild(index)
 		if oldChild == nil {
-			return nil
+			var zero V
+			return zero, false
 		} else {
-			return oldChild.get(hashCode>>5, key, equals)
+			return oldChild.get(hashCode, shift+5, key, equals)
 		}
 	}
 }
 
-func (this *node) contains(hashCode HashCode, key Object, equals EqualsFunc) bool {
-	if hashCode == 0 {
+func (this *node[K, V]) contains(hashCode HashCode, shift uint, key K, equals EqualsFunc[K]) bool {
+	if hashCode>>shift == 0 {
 		return this.containsValueForKey(key, equals)
 	} else {
-		index := indexForHash(hashCode)
+		index := indexForHash(hashCode >> shift)
 		child := this.getChild(index)
-		return child != nil && child.contains(hashCode>>5, key, equals)
+		return child != nil && child.contains(hashCode, shift+5, key, equals)
 	}
 }
 
-func (this *node) delete(hashCode HashCode, key Object, equals EqualsFunc) (*node, int) {
-	if hashCode == 0 {
+func (this *node[K, V]) delete(hashCode HashCode, shift uint, key K, equals EqualsFunc[K]) (*node[K, V], int) {
+	if hashCode>>shift == 0 {
 		return this.deleteKey(key, equals)
 	} else {
-		index := indexForHash(hashCode)
+		index := indexForHash(hashCode >> shift)
 		oldChild := this.getChild(index)
 		if oldChild == nil {
 			return this, 0
 		} else {
-			newChild, delta := oldChild.delete(hashCode>>5, key, equals)
+			newChild, delta := oldChild.delete(hashCode, shift+5, key, equals)
 			if newChild == oldChild {
 				return this, 0
 			} else if newChild == nil {
@@ -95,11 +158,511 @@ func (this *node) delete(hashCode HashCode, key Object, equals EqualsFunc) (*nod
 	}
 }
 
+// update replaces the value for key with the result of calling updater.  If updater
+// returns false the key is removed.  The receiver is returned if nothing changed.
+// valueHash computes the hash code of the new value and may be nil.
+func (this *node[K, V]) update(hashCode HashCode, shift uint, key K, updater func(V, bool) (V, bool), valueHash HashFunc[V], equals EqualsFunc[K]) (*node[K, V], int) {
+	if hashCode>>shift == 0 {
+		oldValue, present := this.getValueForKey(key, equals)
+		newValue, keep := updater(oldValue, present)
+		if keep {
+			return this.setKeyAndValue(hashCode, key, newValue, hashValue(valueHash, newValue), equals)
+		} else if present {
+			return this.deleteKey(key, equals)
+		} else {
+			return this, 0
+		}
+	} else {
+		index := indexForHash(hashCode >> shift)
+		oldChild := this.getChild(index)
+		if oldChild == nil {
+			oldChild = emptyNode[K, V]()
+		}
+		newChild, delta := oldChild.update(hashCode, shift+5, key, updater, valueHash, equals)
+		if newChild == oldChild {
+			return this, delta
+		} else if newChild == nil {
+			return this.deleteChild(index), delta
+		} else {
+			return this.setChild(index, newChild), delta
+		}
+	}
+}
+
+// nodeMerger combines the nodes of two tries that share the same layout.  resolve
+// is called for keys found in both tries and returns false to drop the key.  If
+// resolve is nil those keys keep the value from left.  valueHash computes the hash
+// codes of resolved values and may be nil.  sharedNodes controls how a subtree
+// found in both tries is handled without visiting its keys.  If unmatched is not
+// nil it is called for every key found in only one trie that merge adds or
+// intersect removes.
+type nodeMerger[K any, V any] struct {
+	equals      EqualsFunc[K]
+	resolve     func(key K, left V, right V) (V, bool)
+	valueHash   HashFunc[V]
+	sharedNodes sharedNodePolicy
+	unmatched   func(key K, value V)
+}
+
+type sharedNodePolicy int
+
+const (
+	resolveSharedNodes sharedNodePolicy = iota
+	keepSharedNodes
+	dropSharedNodes
+)
+
+// merge returns a node containing the keys from both left and right along with
+// the change in the number of keys relative to left.  Subtrees present on only
+// one side are reused as is.  left is returned if the result matches it.
+func (this *nodeMerger[K, V]) merge(left *node[K, V], right *node[K, V]) (*node[K, V], int) {
+	if left == right {
+		switch this.sharedNodes {
+		case keepSharedNodes:
+			return left, 0
+		case dropSharedNodes:
+			return nil, -left.count()
+		}
+	}
+
+	delta := 0
+	newKeys := left.keys
+	for kvp := right.keys; kvp != nil; kvp = kvp.next {
+		var d int
+		if leftValue, present := left.getValueForKey(kvp.key, this.equals); !present {
+			newKeys, d = newKeys.assign(kvp.hash, kvp.key, kvp.value, kvp.valueHash, this.equals)
+			this.visitUnmatched(kvp.key, kvp.value)
+		} else if this.resolve == nil {
+			continue
+		} else if value, keep := this.resolve(kvp.key, leftValue, kvp.value); keep {
+			newKeys, d = newKeys.assign(kvp.hash, kvp.key, value, hashValue(this.valueHash, value), this.equals)
+		} else {
+			newKeys, d = newKeys.delete(kvp.key, this.equals)
+		}
+		delta += d
+	}
+
+	changed := newKeys != left.keys
+	var bitmask uint32
+	var children []*node[K, V]
+	for remaining := left.bitmask | right.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		leftChild, rightChild := left.getChild(index), right.getChild(index)
+		child := leftChild
+		if leftChild == nil {
+			child = rightChild
+			delta += rightChild.count()
+			this.visitUnmatchedNode(rightChild)
+		} else if rightChild != nil {
+			var d int
+			child, d = this.merge(leftChild, rightChild)
+			delta += d
+		}
+		if child != leftChild {
+			changed = true
+		}
+		if child != nil {
+			bitmask |= indexBit(index)
+			children = append(children, child)
+		}
+	}
+
+	if !changed {
+		return left, 0
+	} else if newKeys == nil && bitmask == 0 {
+		return nil, delta
+	} else {
+		return newNode(newKeys, bitmask, children), delta
+	}
+}
+
+// intersect returns a node containing only the keys found in both left and right
+// along with the change in the number of keys relative to left.  The value of each
+// key is chosen by resolve.  left is returned if the result matches it.
+func (this *nodeMerger[K, V]) intersect(left *node[K, V], right *node[K, V]) (*node[K, V], int) {
+	if left == right {
+		switch this.sharedNodes {
+		case keepSharedNodes:
+			return left, 0
+		case dropSharedNodes:
+			return nil, -left.count()
+		}
+	}
+
+	delta := 0
+	newKeys := left.keys
+	for kvp := left.keys; kvp != nil; kvp = kvp.next {
+		var d int
+		if rightValue, present := right.getValueForKey(kvp.key, this.equals); !present {
+			newKeys, d = newKeys.delete(kvp.key, this.equals)
+			this.visitUnmatched(kvp.key, kvp.value)
+		} else if this.resolve == nil {
+			continue
+		} else if value, keep := this.resolve(kvp.key, kvp.value, rightValue); keep {
+			newKeys, d = newKeys.assign(kvp.hash, kvp.key, value, hashValue(this.valueHash, value), this.equals)
+		} else {
+			newKeys, d = newKeys.delete(kvp.key, this.equals)
+		}
+		delta += d
+	}
+
+	changed := newKeys != left.keys
+	var bitmask uint32
+	var children []*node[K, V]
+	for remaining := left.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		leftChild, rightChild := left.getChild(index), right.getChild(index)
+		var child *node[K, V]
+		if rightChild == nil {
+			delta -= leftChild.count()
+			this.visitUnmatchedNode(leftChild)
+		} else {
+			var d int
+			child, d = this.intersect(leftChild, rightChild)
+			delta += d
+		}
+		if child != leftChild {
+			changed = true
+		}
+		if child != nil {
+			bitmask |= indexBit(index)
+			children = append(children, child)
+		}
+	}
+
+	if !changed {
+		return left, 0
+	} else if newKeys == nil && bitmask == 0 {
+		return nil, delta
+	} else {
+		return newNode(newKeys, bitmask, children), delta
+	}
+}
+
+func (this *nodeMerger[K, V]) visitUnmatched(key K, value V) {
+	if this.unmatched != nil {
+		this.unmatched(key, value)
+	}
+}
+
+func (this *nodeMerger[K, V]) visitUnmatchedNode(n *node[K, V]) {
+	if this.unmatched != nil {
+		n.forEach(this.unmatched)
+	}
+}
+
+// difference returns a node containing the keys of this node that are not in other
+// along with the change in the number of keys.  Both nodes must come from tries
+// that share the same layout.
+func (this *node[K, V]) difference(other *node[K, V], equals EqualsFunc[K]) (*node[K, V], int) {
+	if this == other {
+		return nil, -this.count()
+	}
+
+	newKeys, delta := this.keys.filter(func(key K, _ V) bool {
+		return !other.containsValueForKey(key, equals)
+	})
+
+	changed := newKeys != this.keys
+	var bitmask uint32
+	var children []*node[K, V]
+	for remaining := this.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		oldChild, otherChild := this.getChild(index), other.getChild(index)
+		newChild := oldChild
+		if otherChild != nil {
+			var d int
+			newChild, d = oldChild.difference(otherChild, equals)
+			delta += d
+		}
+		if newChild != oldChild {
+			changed = true
+		}
+		if newChild != nil {
+			bitmask |= indexBit(index)
+			children = append(children, newChild)
+		}
+	}
+
+	if !changed {
+		return this, 0
+	} else if newKeys == nil && bitmask == 0 {
+		return nil, delta
+	} else {
+		return newNode(newKeys, bitmask, children), delta
+	}
+}
+
+// isSubsetOf reports whether every key of this node and its descendants is also
+// found in other.  Both nodes must come from tries that share the same layout.
+func (this *node[K, V]) isSubsetOf(other *node[K, V], equals EqualsFunc[K]) bool {
+	if this == other {
+		return true
+	}
+	for kvp := this.keys; kvp != nil; kvp = kvp.next {
+		if !other.containsValueForKey(kvp.key, equals) {
+			return false
+		}
+	}
+	if this.bitmask&other.bitmask != this.bitmask {
+		return false
+	}
+	for remaining := this.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		if !this.getChild(index).isSubsetOf(other.getChild(index), equals) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalTo reports whether this node and other contain the same keys and values.
+// Both nodes must come from tries that share the same layout and value hash
+// function, which must be consistent with valueEquals.
+func (this *node[K, V]) equalTo(other *node[K, V], equals EqualsFunc[K], valueEquals EqualsFunc[V]) bool {
+	if this == other {
+		return true
+	}
+	if this.nodeTotals != other.nodeTotals || this.bitmask != other.bitmask {
+		return false
+	}
+	keyCount := 0
+	for kvp := this.keys; kvp != nil; kvp = kvp.next {
+		otherValue, present := other.getValueForKey(kvp.key, equals)
+		if !present || !valueEquals(kvp.value, otherValue) {
+			return false
+		}
+		keyCount++
+	}
+	for kvp := other.keys; kvp != nil; kvp = kvp.next {
+		keyCount--
+	}
+	if keyCount != 0 {
+		return false
+	}
+	for i, child := range this.children {
+		if !child.equalTo(other.children[i], equals, valueEquals) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter returns a node containing only the keys for which keep returns true along
+// with the change in the number of keys.  Subtrees in which every key is kept are
+// reused as is.
+func (this *node[K, V]) filter(keep MapPredicate[K, V]) (*node[K, V], int) {
+	newKeys, delta := this.keys.filter(keep)
+	changed := newKeys != this.keys
+	var bitmask uint32
+	var children []*node[K, V]
+	for remaining := this.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		oldChild := this.getChild(index)
+		newChild, d := oldChild.filter(keep)
+		delta += d
+		if newChild != oldChild {
+			changed = true
+		}
+		if newChild != nil {
+			bitmask |= indexBit(index)
+			children = append(children, newChild)
+		}
+	}
+
+	if !changed {
+		return this, 0
+	} else if newKeys == nil && bitmask == 0 {
+		return nil, delta
+	} else {
+		return newNode(newKeys, bitmask, children), delta
+	}
+}
+
+// partition splits this node into a node containing the keys for which p returns
+// true and a node containing the rest.  Either result is nil if it has no keys and
+// is the receiver if it has every key.  The final result is the number of keys in
+// the second node.
+func (this *node[K, V]) partition(p MapPredicate[K, V]) (*node[K, V], *node[K, V], int) {
+	var matchingKeys, otherKeys *keyValueList[K, V]
+	otherCount, keyCount := 0, 0
+	for kvp := this.keys; kvp != nil; kvp = kvp.next {
+		if p(kvp.key, kvp.value) {
+			matchingKeys = &keyValueList[K, V]{hash: kvp.hash, valueHash: kvp.valueHash, key: kvp.key, value: kvp.value, next: matchingKeys}
+		} else {
+			otherKeys = &keyValueList[K, V]{hash: kvp.hash, valueHash: kvp.valueHash, key: kvp.key, value: kvp.value, next: otherKeys}
+			otherCount++
+		}
+		keyCount++
+	}
+	if otherCount == 0 {
+		matchingKeys = this.keys
+	} else if otherCount == keyCount {
+		otherKeys = this.keys
+	}
+
+	matchingChanged, otherChanged := otherCount > 0, otherCount < keyCount
+	var matchingBitmask, otherBitmask uint32
+	var matchingChildren, otherChildren []*node[K, V]
+	for remaining := this.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		child := this.getChild(index)
+		matchingChild, otherChild, count := child.partition(p)
+		otherCount += count
+		if matchingChild != child {
+			matchingChanged = true
+		}
+		if otherChild != child {
+			otherChanged = true
+		}
+		if matchingChild != nil {
+			matchingBitmask |= indexBit(index)
+			matchingChildren = append(matchingChildren, matchingChild)
+		}
+		if otherChild != nil {
+			otherBitmask |= indexBit(index)
+			otherChildren = append(otherChildren, otherChild)
+		}
+	}
+
+	var matching, other *node[K, V]
+	if !matchingChanged {
+		matching = this
+	} else if matchingKeys != nil || matchingBitmask != 0 {
+		matching = newNode(matchingKeys, matchingBitmask, matchingChildren)
+	}
+	if !otherChanged {
+		other = this
+	} else if otherKeys != nil || otherBitmask != 0 {
+		other = newNode(otherKeys, otherBitmask, otherChildren)
+	}
+	return matching, other, otherCount
+}
+
+// mapValues returns a node in which every value is replaced by the result of
+// calling f along with the change in the number of keys.  Keys for which f returns
+// false are removed.  Subtrees in which f keeps every key with the same value are
+// reused.  valueHash computes the hash codes of the new values and may be nil.
+func (this *node[K, V]) mapValues(f func(K, V) (V, bool), valueHash HashFunc[V]) (*node[K, V], int) {
+	changed := false
+	delta := 0
+	var newKeys *keyValueList[K, V]
+	for kvp := this.keys; kvp != nil; kvp = kvp.next {
+		newValue, keep := f(kvp.key, kvp.value)
+		if !keep {
+			changed = true
+			delta--
+			continue
+		}
+		if !sameValue(newValue, kvp.value) {
+			changed = true
+		}
+		newKeys = &keyValueList[K, V]{hash: kvp.hash, valueHash: hashValue(valueHash, newValue), key: kvp.key, value: newValue, next: newKeys}
+	}
+	if !changed {
+		newKeys = this.keys
+	}
+
+	var bitmask uint32
+	var children []*node[K, V]
+	for remaining := this.bitmask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		oldChild := this.getChild(index)
+		newChild, d := oldChild.mapValues(f, valueHash)
+		delta += d
+		if newChild != oldChild {
+			changed = true
+		}
+		if newChild != nil {
+			bitmask |= indexBit(index)
+			children = append(children, newChild)
+		}
+	}
+
+	if !changed {
+		return this, 0
+	} else if newKeys == nil && bitmask == 0 {
+		return nil, delta
+	} else {
+		return newNode(newKeys, bitmask, children), delta
+	}
+}
+
+// batchEntry is a single change applied by applyBatch.
+type batchEntry[K any, V any] struct {
+	hash      HashCode
+	valueHash HashCode
+	key       K
+	value     V
+	delete    bool
+}
+
+// applyBatch applies a batch of changes in order, grouping them by the hash bits
+// that select each child so that every affected node is rebuilt only once.
+// shift is the number of hash bits consumed by the ancestors of this node.  The
+// receiver is returned if nothing changed.
+func (this *node[K, V]) applyBatch(batch []batchEntry[K, V], shift uint, equals EqualsFunc[K]) (*node[K, V], int) {
+	delta := 0
+	newKeys := this.keys
+	var groups [32][]batchEntry[K, V]
+	var groupMask uint32
+	for _, entry := range batch {
+		if entry.hash>>shift == 0 {
+			var d int
+			if entry.delete {
+				newKeys, d = newKeys.delete(entry.key, equals)
+			} else {
+				newKeys, d = newKeys.assign(entry.hash, entry.key, entry.value, entry.valueHash, equals)
+			}
+			delta += d
+		} else {
+			index := indexForHash(entry.hash >> shift)
+			groups[index] = append(groups[index], entry)
+			groupMask |= indexBit(index)
+		}
+	}
+
+	changed := newKeys != this.keys
+	var bitmask uint32
+	var children []*node[K, V]
+	for remaining := this.bitmask | groupMask; remaining != 0; remaining &= remaining - 1 {
+		index := bits.TrailingZeros32(remaining)
+		oldChild := this.getChild(index)
+		newChild := oldChild
+		if len(groups[index]) > 0 {
+			if oldChild == nil {
+				oldChild = emptyNode[K, V]()
+			}
+			var d int
+			newChild, d = oldChild.applyBatch(groups[index], shift+5, equals)
+			delta += d
+			if newChild != nil && newChild.isEmpty() {
+				newChild = nil
+			}
+		}
+		if newChild != this.getChild(index) {
+			changed = true
+		}
+		if newChild != nil {
+			bitmask |= indexBit(index)
+			children = append(children, newChild)
+		}
+	}
+
+	if !changed {
+		return this, 0
+	} else if newKeys == nil && bitmask == 0 {
+		return nil, delta
+	} else {
+		return newNode(newKeys, bitmask, children), delta
+	}
+}
+
 func indexForHash(hashCode HashCode) int {
 	return int(hashCode & 0x0f)
 }
 
-func (this *node) containsValueForKey(key Object, equals EqualsFunc) bool {
+func (this *node[K, V]) containsValueForKey(key K, equals EqualsFunc[K]) bool {
 	for kvp := this.keys; kvp != nil; kvp = kvp.next {
 		if equals(key, kvp.key) {
 			return true
@@ -108,74 +671,201 @@ func (this *node) containsValueForKey(key Object, equals EqualsFunc) bool {
 	return false
 }
 
-func (this *node) getValueForKey(key Object, equals EqualsFunc) Object {
+func (this *node[K, V]) getValueForKey(key K, equals EqualsFunc[K]) (V, bool) {
 	for kvp := this.keys; kvp != nil; kvp = kvp.next {
 		if equals(key, kvp.key) {
-			return kvp.value
+			return kvp.value, true
 		}
 	}
-	return nil
+	var zero V
+	return zero, false
 }
 
-func (this *node) setKeyAndValue(key Object, value Object, equals EqualsFunc) (*node, int) {
-	var newKeys *keyValueList
-	delta := 0
-	if this.keys == nil {
-		newKeys = &keyValueList{key: key, value: value}
-		delta = 1
+func (this *node[K, V]) setKeyAndValue(hashCode HashCode, key K, value V, valueHash HashCode, equals EqualsFunc[K]) (*node[K, V], int) {
+	newKeys, delta := this.keys.assign(hashCode, key, value, valueHash, equals)
+	if newKeys == this.keys {
+		return this, 0
+	}
+	return this.withKeys(newKeys), delta
+}
+
+func (this *node[K, V]) deleteKey(key K, equals EqualsFunc[K]) (*node[K, V], int) {
+	newKeys, delta := this.keys.delete(key, equals)
+	if newKeys == this.keys {
+		return this, 0
+	} else if newKeys == nil && this.childCount() == 0 {
+		return nil, delta
 	} else {
-		changed := false
-		for kvp := this.keys; kvp != nil; kvp = kvp.next {
-			if equals(kvp.key, key) {
-				if kvp.value == value {
-					return this, 0
-				}
-				newKeys = &keyValueList{key: key, value: value, next: newKeys}
-				changed = true
-			} else {
-				newKeys = &keyValueList{key: kvp.key, value: kvp.value, next: newKeys}
-			}
-		}
-		if !changed {
-			newKeys = &keyValueList{key: key, value: value, next: this.keys}
-			delta = 1
-		}
+		return this.withKeys(newKeys), delta
 	}
+}
+
+func (this *node[K, V]) withKeys(newKeys *keyValueList[K, V]) *node[K, V] {
 	newNode := *this
 	newNode.keys = newKeys
-	return &newNode, delta
+	newNode.nodeTotals = this.nodeTotals.plus(newKeys.totals()).minus(this.keys.totals())
+	return &newNode
 }
 
-func (this *node) deleteKey(key Object, equals EqualsFunc) (*node, int) {
-	if this.keys == nil {
-		return this, 0
+// assign returns a list containing the key and value along with the change in
+// the number of keys.  The receiver is returned if the key already has the value.
+func (this *keyValueList[K, V]) assign(hashCode HashCode, key K, value V, valueHash HashCode, equals EqualsFunc[K]) (*keyValueList[K, V], int) {
+	if this == nil {
+		return &keyValueList[K, V]{hash: hashCode, valueHash: valueHash, key: key, value: value}, 1
 	}
 
 	changed := false
-	var newKeys *keyValueList
-	for kvp := this.keys; kvp != nil; kvp = kvp.next {
+	var newKeys *keyValueList[K, V]
+	for kvp := this; kvp != nil; kvp = kvp.next {
+		if equals(kvp.key, key) {
+			if sameValue(kvp.value, value) {
+				return this, 0
+			}
+			newKeys = &keyValueList[K, V]{hash: hashCode, valueHash: valueHash, key: key, value: value, next: newKeys}
+			changed = true
+		} else {
+			newKeys = &keyValueList[K, V]{hash: kvp.hash, valueHash: kvp.valueHash, key: kvp.key, value: kvp.value, next: newKeys}
+		}
+	}
+	if !changed {
+		return &keyValueList[K, V]{hash: hashCode, valueHash: valueHash, key: key, value: value, next: this}, 1
+	}
+	return newKeys, 0
+}
+
+// delete returns a list without the key along with the change in the number of
+// keys.  The receiver is returned if the key is not in the list.
+func (this *keyValueList[K, V]) delete(key K, equals EqualsFunc[K]) (*keyValueList[K, V], int) {
+	changed := false
+	var newKeys *keyValueList[K, V]
+	for kvp := this; kvp != nil; kvp = kvp.next {
 		if equals(kvp.key, key) {
 			changed = true
 		} else {
-			newKeys = &keyValueList{key: kvp.key, value: kvp.value, next: newKeys}
+			newKeys = &keyValueList[K, V]{hash: kvp.hash, valueHash: kvp.valueHash, key: kvp.key, value: kvp.value, next: newKeys}
 		}
 	}
 	if !changed {
 		return this, 0
-	} else if newKeys == nil && this.childCount() == 0 {
-		return nil, -1
-	} else {
-		newNode := *this
-		newNode.keys = newKeys
-		return &newNode, -1
 	}
+	return newKeys, -1
 }
 
-func (this *node) childCount() int {
+// filter returns a list containing only the keys for which keep returns true
+// along with the change in the number of keys.  The receiver is returned if every
+// key is kept.
+func (this *keyValueList[K, V]) filter(keep func(K, V) bool) (*keyValueList[K, V], int) {
+	changed := false
+	delta := 0
+	var newKeys *keyValueList[K, V]
+	for kvp := this; kvp != nil; kvp = kvp.next {
+		if keep(kvp.key, kvp.value) {
+			newKeys = &keyValueList[K, V]{hash: kvp.hash, valueHash: kvp.valueHash, key: kvp.key, value: kvp.value, next: newKeys}
+		} else {
+			changed = true
+			delta--
+		}
+	}
+	if !changed {
+		return this, 0
+	}
+	return newKeys, delta
+}
+
+// totals returns the totals of the keys in the list.
+func (this *keyValueList[K, V]) totals() nodeTotals {
+	var answer nodeTotals
+	for kvp := this; kvp != nil; kvp = kvp.next {
+		answer.contentHash += elementHash(kvp.hash)
+		answer.entryContentHash += entryHash(kvp.hash, kvp.valueHash)
+		answer.size++
+	}
+	return answer
+}
+
+// sameValue reports whether two values are known to be identical.  Values whose
+// dynamic types can be compared with == are compared that way.  Other values are
+// compared using identicalValues.
+func sameValue[V any](a V, b V) bool {
+	x, y := any(a), any(b)
+	if x == nil || y == nil {
+		return x == y
+	}
+	xv, yv := reflect.ValueOf(x), reflect.ValueOf(y)
+	if !xv.Comparable() || !yv.Comparable() {
+		return identicalValues(xv, yv)
+	}
+	return x == y
+}
+
+// identicalValues reports whether two values are known to be identical.  Slices
+// are identical if they share a backing array and have the same length and
+// capacity, and maps and channels if they are the same object.  Structs, arrays and
+// interfaces are identical if all of their parts are.  Functions are never
+// considered identical.
+func identicalValues(x reflect.Value, y reflect.Value) bool {
+	if x.Type() != y.Type() {
+		return false
+	}
+	switch x.Kind() {
+	case reflect.Slice:
+		return x.Pointer() == y.Pointer() && x.Len() == y.Len() && x.Cap() == y.Cap()
+	case reflect.Map, reflect.Chan, reflect.Pointer, reflect.UnsafePointer:
+		return x.Pointer() == y.Pointer()
+	case reflect.Func:
+		return false
+	case reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() && y.IsNil()
+		}
+		return identicalValues(x.Elem(), y.Elem())
+	case reflect.Array:
+		for i := 0; i < x.Len(); i++ {
+			if !identicalValues(x.Index(i), y.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < x.NumField(); i++ {
+			if !identicalValues(x.Field(i), y.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return x.Equal(y)
+	}
+}
+
+// elementHash scrambles the hash code of a single key so that the sum of the
+// results for all keys in a collection is well distributed.
+func elementHash(hashCode HashCode) HashCode {
+	h := uint32(hashCode)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return HashCode(h)
+}
+
+// entryHash combines the hash codes of a key and its value into a single hash code
+// suitable for summing across all entries of a map.
+func entryHash(keyHash HashCode, valueHash HashCode) HashCode {
+	return elementHash(31*keyHash + valueHash)
+}
+
+func (this *node[K, V]) childCount() int {
 	return bits.OnesCount32(this.bitmask)
 }
 
-func (this *node) getChild(index int) *node {
+// count returns the number of keys in this node and all of its descendants.
+func (this *node[K, V]) count() int {
+	return this.size
+}
+
+func (this *node[K, V]) getChild(index int) *node[K, V] {
 	indexBit := indexBit(index)
 	if this.bitmask&indexBit == 0 {
 		return nil
@@ -185,7 +875,7 @@ func (this *node) getChild(index int) *node {
 	}
 }
 
-func (this *node) realIndex(indexBit uint32) int {
+func (this *node[K, V]) realIndex(indexBit uint32) int {
 	trailingBits := indexBit - 1
 	realIndex := bits.OnesCount32(this.bitmask & trailingBits)
 	return realIndex
@@ -196,21 +886,22 @@ func indexBit(index int) uint32 {
 	return indexBit
 }
 
-func (this *node) setChild(index int, child *node) *node {
+func (this *node[K, V]) setChild(index int, child *node[K, V]) *node[K, V] {
 	newNode := *this
+	newNode.nodeTotals = this.nodeTotals.plus(child.nodeTotals).minus(this.childTotals(index))
 	indexBit := indexBit(index)
 	if this.children == nil {
-		newNode.children = make([]*node, 1)
+		newNode.children = make([]*node[K, V], 1)
 		newNode.children[0] = child
 		newNode.bitmask = indexBit
 	} else {
 		realIndex := this.realIndex(indexBit)
 		if this.bitmask&indexBit != 0 {
-			newNode.children = make([]*node, len(this.children))
+			newNode.children = make([]*node[K, V], len(this.children))
 			copy(newNode.children, this.children)
 			newNode.children[realIndex] = child
 		} else {
-			newNode.children = make([]*node, len(this.children)+1)
+			newNode.children = make([]*node[K, V], len(this.children)+1)
 			copy(newNode.children, this.children[0:realIndex])
 			newNode.children[realIndex] = child
 			copy(newNode.children[realIndex+1:], this.children[realIndex:])
@@ -220,8 +911,9 @@ func (this *node) setChild(index int, child *node) *node {
 	return &newNode
 }
 
-func (this *node) deleteChild(index int) *node {
+func (this *node[K, V]) deleteChild(index int) *node[K, V] {
 	newNode := *this
+	newNode.nodeTotals = this.nodeTotals.minus(this.childTotals(index))
 	if this.childCount() == 1 {
 		if this.keys == nil {
 			return nil
@@ -232,7 +924,7 @@ func (this *node) deleteChild(index int) *node {
 	} else {
 		indexBit := indexBit(index)
 		realIndex := this.realIndex(indexBit)
-		newNode.children = make([]*node, len(this.children)-1)
+		newNode.children = make([]*node[K, V], len(this.children)-1)
 		copy(newNode.children, this.children[0:realIndex])
 		copy(newNode.children[realIndex:], this.children[realIndex+1:])
 		newNode.bitmask &= ^indexBit
@@ -240,7 +932,130 @@ func (this *node) deleteChild(index int) *node {
 	return &newNode
 }
 
-func (this *node) forEach(v MapVisitor) {
+func (this *node[K, V]) childTotals(index int) nodeTotals {
+	if child := this.getChild(index); child != nil {
+		return child.nodeTotals
+	}
+	return nodeTotals{}
+}
+
+func newEditToken() *editToken {
+	return &editToken{}
+}
+
+// editable returns a node owned by edit with the same contents as this node.
+// The receiver is returned if it is already owned by edit.
+func (this *node[K, V]) editable(edit *editToken) *node[K, V] {
+	if this.edit == edit {
+		return this
+	}
+	newNode := *this
+	newNode.edit = edit
+	if this.children != nil {
+		newNode.children = make([]*node[K, V], len(this.children), len(this.children)+1)
+		copy(newNode.children, this.children)
+	}
+	return &newNode
+}
+
+func (this *node[K, V]) transientAssign(edit *editToken, hashCode HashCode, shift uint, key K, value V, valueHash HashCode, equals EqualsFunc[K]) (*node[K, V], int) {
+	if hashCode>>shift == 0 {
+		newKeys, delta := this.keys.assign(hashCode, key, value, valueHash, equals)
+		if newKeys == this.keys {
+			return this, 0
+		}
+		return this.transientSetKeys(edit, newKeys), delta
+	} else {
+		index := indexForHash(hashCode >> shift)
+		oldChild := this.getChild(index)
+		if oldChild == nil {
+			oldChild = &node[K, V]{edit: edit}
+		}
+		// the child may be modified in place so remember its totals before the change
+		oldTotals := this.childTotals(index)
+		newChild, delta := oldChild.transientAssign(edit, hashCode, shift+5, key, value, valueHash, equals)
+		if newChild == oldChild && newChild.nodeTotals == oldTotals && this.bitmask&indexBit(index) != 0 {
+			return this, delta
+		}
+		newNode := this.transientSetChild(edit, index, newChild)
+		newNode.nodeTotals = newNode.nodeTotals.plus(newChild.nodeTotals).minus(oldTotals)
+		return newNode, delta
+	}
+}
+
+func (this *node[K, V]) transientDelete(edit *editToken, hashCode HashCode, shift uint, key K, equals EqualsFunc[K]) (*node[K, V], int) {
+	if hashCode>>shift == 0 {
+		newKeys, delta := this.keys.delete(key, equals)
+		if newKeys == this.keys {
+			return this, 0
+		} else if newKeys == nil && this.childCount() == 0 {
+			return nil, delta
+		}
+		return this.transientSetKeys(edit, newKeys), delta
+	} else {
+		index := indexForHash(hashCode >> shift)
+		oldChild := this.getChild(index)
+		if oldChild == nil {
+			return this, 0
+		}
+		oldTotals := oldChild.nodeTotals
+		newChild, delta := oldChild.transientDelete(edit, hashCode, shift+5, key, equals)
+		if newChild == oldChild && newChild.nodeTotals == oldTotals {
+			return this, delta
+		}
+		var newNode *node[K, V]
+		if newChild == nil {
+			newNode = this.transientDeleteChild(edit, index)
+			if newNode == nil {
+				return nil, delta
+			}
+			newNode.nodeTotals = newNode.nodeTotals.minus(oldTotals)
+		} else {
+			newNode = this.transientSetChild(edit, index, newChild)
+			newNode.nodeTotals = newNode.nodeTotals.plus(newChild.nodeTotals).minus(oldTotals)
+		}
+		return newNode, delta
+	}
+}
+
+func (this *node[K, V]) transientSetKeys(edit *editToken, newKeys *keyValueList[K, V]) *node[K, V] {
+	newNode := this.editable(edit)
+	newNode.nodeTotals = newNode.nodeTotals.plus(newKeys.totals()).minus(newNode.keys.totals())
+	newNode.keys = newKeys
+	return newNode
+}
+
+func (this *node[K, V]) transientSetChild(edit *editToken, index int, child *node[K, V]) *node[K, V] {
+	newNode := this.editable(edit)
+	indexBit := indexBit(index)
+	realIndex := newNode.realIndex(indexBit)
+	if newNode.bitmask&indexBit != 0 {
+		newNode.children[realIndex] = child
+	} else {
+		newNode.children = slices.Insert(newNode.children, realIndex, child)
+		newNode.bitmask |= indexBit
+	}
+	return newNode
+}
+
+func (this *node[K, V]) transientDeleteChild(edit *editToken, index int) *node[K, V] {
+	if this.childCount() == 1 && this.keys == nil {
+		return nil
+	}
+	newNode := this.editable(edit)
+	if newNode.childCount() == 1 {
+		newNode.children = nil
+		newNode.bitmask = 0
+	} else {
+		indexBit := indexBit(index)
+		realIndex := newNode.realIndex(indexBit)
+		newNode.children = slices.Delete(newNode.children, realIndex, realIndex+1)
+		newNode.bitmask &= ^indexBit
+	}
+	return newNode
+}
+
+func (this *node[K, V]) forEach(v MapVisitor[K, V]) {
 	for kvp := this.keys; kvp != nil; kvp = kvp.next {
 		v(kvp.key, kvp.value)
 	}
@@ -251,7 +1066,23 @@ func (this *node) forEach(v MapVisitor) {
 	}
 }
 
-func (this *node) createIteratorState(nextState *iteratorState) *iteratorState {
+// forEachWhile calls v for each key and value until v returns false.  It returns
+// false if the visit was stopped early.
+func (this *node[K, V]) forEachWhile(v MapPredicate[K, V]) bool {
+	for kvp := this.keys; kvp != nil; kvp = kvp.next {
+		if !v(kvp.key, kvp.value) {
+			return false
+		}
+	}
+	for _, child := range this.children {
+		if !child.forEachWhile(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *node[K, V]) createIteratorState(nextState *iteratorState[K, V]) *iteratorState[K, V] {
 	if this.isEmpty() {
 		return nextState
 	} else {
@@ -261,11 +1092,11 @@ func (this *node) createIteratorState(nextState *iteratorState) *iteratorState {
 		} else {
 			startingIndex = -1
 		}
-		return &iteratorState{next: nextState, currentNode: this, currentIndex: startingIndex, currentKey: this.keys}
+		return &iteratorState[K, V]{next: nextState, currentNode: this, currentIndex: startingIndex, currentKey: this.keys}
 	}
 }
 
-func (this *node) next(state *iteratorState) (*iteratorState, Object, Object) {
+func (this *node[K, V]) next(state *iteratorState[K, V]) (*iteratorState[K, V], K, V) {
 	if state == nil || state.currentNode != this {
 		state = this.createIteratorState(state)
 	}
@@ -292,7 +1123,7 @@ func (this *node) next(state *iteratorState) (*iteratorState, Object, Object) {
 	}
 }
 
-func (this *node) checkInvariants(hash HashFunc, equals EqualsFunc, shift uint, report reporter) {
+func (this *node[K, V]) checkInvariants(hash HashFunc[K], equals EqualsFunc[K], shift uint, report reporter) {
 	for kvp := this.keys; kvp != nil; kvp = kvp.next {
 		for other := kvp.next; other != nil; other = other.next {
 			if equals(kvp.key, other.key) {
@@ -302,6 +1133,23 @@ func (this *node) checkInvariants(hash HashFunc, equals EqualsFunc, shift uint,
 		if shiftedHash := hash(kvp.key) >> shift; shiftedHash != 0 {
 			report(fmt.Sprintf("key with non-zero hash detected: key=%v shiftedHash=%d", kvp.key, shiftedHash))
 		}
+		if kvp.hash != hash(kvp.key) {
+			report(fmt.Sprintf("stored hash differs from key hash: key=%v stored=%d actual=%d", kvp.key, kvp.hash, hash(kvp.key)))
+		}
+	}
+
+	totals := this.keys.totals()
+	for _, c := range this.children {
+		totals = totals.plus(c.nodeTotals)
+	}
+	if totals.contentHash != this.contentHash {
+		report(fmt.Sprintf("content hash mismatch: expected=%d actual=%d", totals.contentHash, this.contentHash))
+	}
+	if totals.entryContentHash != this.entryContentHash {
+		report(fmt.Sprintf("entry content hash mismatch: expected=%d actual=%d", totals.entryContentHash, this.entryContentHash))
+	}
+	if totals.size != this.size {
+		report(fmt.Sprintf("size mismatch: expected=%d actual=%d", totals.size, this.size))
 	}
 
 	if this.bitmask != 0 && this.children == nil {
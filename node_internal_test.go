@@ -2,6 +2,7 @@ package immutableMap
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"testing"
@@ -19,6 +20,14 @@ func stringHash(a Object) HashCode {
 	return val
 }
 
+func typedStringEquals(a string, b string) bool {
+	return a == b
+}
+
+func typedStringHash(a string) HashCode {
+	return stringHash(a)
+}
+
 func numberHash(a Object) HashCode {
 	i, _ := strconv.Atoi(a.(string))
 	return HashCode(i)
@@ -124,6 +133,660 @@ func TestSet(t *testing.T) {
 	}
 }
 
+func TestTypedMap(t *testing.T) {
+	m := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := -500; i <= 500; i++ {
+		m = m.Assign(val(i), i)
+	}
+	m.checkInvariants(createReporter(t))
+
+	for i := -500; i <= 500; i++ {
+		if v := m.Get(val(i)); v != i {
+			t.Error(fmt.Sprintf("expected %v but got %v for key %v", i, v, val(i)))
+		}
+	}
+	if v := m.Get("missing"); v != 0 {
+		t.Error(fmt.Sprintf("expected zero value but got %v", v))
+	}
+
+	keys := m.Keys()
+	if keys.Size() != m.Size() {
+		t.Error(fmt.Sprintf("key set size mismatch: expected=%d actual=%d", m.Size(), keys.Size()))
+	}
+	keys.checkInvariants(createReporter(t))
+
+	for i := -500; i <= 0; i++ {
+		m = m.Delete(val(i))
+	}
+	m.checkInvariants(createReporter(t))
+	if m.Size() != 500 {
+		t.Error(fmt.Sprintf("expected size 500 but got %d", m.Size()))
+	}
+}
+
+func TestTypedSet(t *testing.T) {
+	s := NewSet[string](typedStringHash, typedStringEquals)
+	for i := 0; i < 100; i++ {
+		s = s.Add(val(i))
+	}
+	s.checkInvariants(createReporter(t))
+
+	evens := NewSet[string](typedStringHash, typedStringEquals)
+	for i := 0; i < 200; i += 2 {
+		evens = evens.Add(val(i))
+	}
+	if size := s.Intersection(evens).Size(); size != 50 {
+		t.Error(fmt.Sprintf("expected intersection size 50 but got %d", size))
+	}
+	if size := s.Union(evens).Size(); size != 150 {
+		t.Error(fmt.Sprintf("expected union size 150 but got %d", size))
+	}
+}
+
+func TestComparableMap(t *testing.T) {
+	type point struct {
+		x, y int
+	}
+	m := NewComparableMap[point, string]()
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 40; y++ {
+			m = m.Assign(point{x, y}, fmt.Sprintf("%d,%d", x, y))
+		}
+	}
+	m.checkInvariants(createReporter(t))
+	if m.Size() != 1600 {
+		t.Error(fmt.Sprintf("expected size 1600 but got %d", m.Size()))
+	}
+	if v := m.Get(point{12, 34}); v != "12,34" {
+		t.Error(fmt.Sprintf("expected 12,34 but got %v", v))
+	}
+
+	s := NewComparableSet[int]()
+	for i := 0; i < 1000; i++ {
+		s = s.Add(i % 250)
+	}
+	s.checkInvariants(createReporter(t))
+	if s.Size() != 250 {
+		t.Error(fmt.Sprintf("expected size 250 but got %d", s.Size()))
+	}
+}
+
+func TestMapLookup(t *testing.T) {
+	m := CreateMap(stringHash, stringEquals)
+	m = m.Assign("a", 1)
+	m = m.Assign("b", nil)
+	m.checkInvariants(createReporter(t))
+
+	if v, ok := m.Lookup("a"); !ok || v != 1 {
+		t.Error(fmt.Sprintf("Lookup mismatch: key=a value=%v ok=%v", v, ok))
+	}
+	if v, ok := m.Lookup("b"); !ok || v != nil {
+		t.Error(fmt.Sprintf("Lookup mismatch: key=b value=%v ok=%v", v, ok))
+	}
+	if v, ok := m.Lookup("c"); ok || v != nil {
+		t.Error(fmt.Sprintf("Lookup mismatch: key=c value=%v ok=%v", v, ok))
+	}
+
+	if !m.ContainsKey("b") {
+		t.Error("ContainsKey returned false for key with nil value")
+	}
+	if m.ContainsKey("c") {
+		t.Error("ContainsKey returned true for missing key")
+	}
+
+	if v := m.GetOrDefault("b", 2); v != nil {
+		t.Error(fmt.Sprintf("GetOrDefault returned %v for key with nil value", v))
+	}
+	if v := m.GetOrDefault("c", 3); v != 3 {
+		t.Error(fmt.Sprintf("GetOrDefault returned %v for missing key", v))
+	}
+
+	calls := 0
+	orElse := func() Object {
+		calls++
+		return 4
+	}
+	if v := m.GetOrElse("a", orElse); v != 1 || calls != 0 {
+		t.Error(fmt.Sprintf("GetOrElse mismatch: value=%v calls=%d", v, calls))
+	}
+	if v := m.GetOrElse("c", orElse); v != 4 || calls != 1 {
+		t.Error(fmt.Sprintf("GetOrElse mismatch: value=%v calls=%d", v, calls))
+	}
+}
+
+func TestMapUpdate(t *testing.T) {
+	m := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 0; i < 100; i++ {
+		m = m.Assign(val(i), i)
+	}
+
+	increment := func(oldValue int, present bool) (int, bool) {
+		return oldValue + 1, true
+	}
+	m2 := m.Update(val(7), increment).Update(val(200), increment)
+	m2.checkInvariants(createReporter(t))
+	if v := m2.Get(val(7)); v != 8 {
+		t.Error(fmt.Sprintf("expected 8 but got %v", v))
+	}
+	if v, ok := m2.Lookup(val(200)); !ok || v != 1 {
+		t.Error(fmt.Sprintf("Lookup mismatch: value=%v ok=%v", v, ok))
+	}
+	if m2.Size() != 101 {
+		t.Error(fmt.Sprintf("expected size 101 but got %d", m2.Size()))
+	}
+
+	remove := func(oldValue int, present bool) (int, bool) {
+		return oldValue, false
+	}
+	m3 := m2.Update(val(7), remove)
+	m3.checkInvariants(createReporter(t))
+	if m3.ContainsKey(val(7)) || m3.Size() != 100 {
+		t.Error(fmt.Sprintf("Update did not remove key: size=%d", m3.Size()))
+	}
+	if m3.Update(val(7), remove) != m3 {
+		t.Error("Update removing a missing key returned a new map")
+	}
+	unchanged := func(oldValue int, present bool) (int, bool) {
+		return oldValue, present
+	}
+	if m3.Update(val(8), unchanged) != m3 {
+		t.Error("Update returning the same value returned a new map")
+	}
+
+	m4 := m3.Upsert(val(8), func(oldValue int, present bool) int {
+		return oldValue * 10
+	})
+	if v := m4.Get(val(8)); v != 80 {
+		t.Error(fmt.Sprintf("expected 80 but got %v", v))
+	}
+
+	calls := 0
+	compute := func(key string) int {
+		calls++
+		return len(key)
+	}
+	if m4.ComputeIfAbsent(val(8), compute) != m4 || calls != 0 {
+		t.Error(fmt.Sprintf("ComputeIfAbsent changed map for present key: calls=%d", calls))
+	}
+	if v := m4.ComputeIfAbsent("abc", compute).Get("abc"); v != 3 || calls != 1 {
+		t.Error(fmt.Sprintf("ComputeIfAbsent mismatch: value=%v calls=%d", v, calls))
+	}
+
+	negate := func(key string, oldValue int) (int, bool) {
+		return -oldValue, oldValue != 9
+	}
+	if m4.ComputeIfPresent("abc", negate) != m4 {
+		t.Error("ComputeIfPresent changed map for missing key")
+	}
+	m5 := m4.ComputeIfPresent(val(5), negate).ComputeIfPresent(val(9), negate)
+	m5.checkInvariants(createReporter(t))
+	if v := m5.Get(val(5)); v != -5 {
+		t.Error(fmt.Sprintf("expected -5 but got %v", v))
+	}
+	if m5.ContainsKey(val(9)) {
+		t.Error("ComputeIfPresent did not remove key")
+	}
+}
+
+func TestMapUpdateUncomparableValues(t *testing.T) {
+	m := NewMap[string, []int](typedStringHash, typedStringEquals)
+	for i := 0; i < 100; i++ {
+		m = m.Assign(val(i), []int{i})
+	}
+
+	if m.Upsert(val(7), func(oldValue []int, present bool) []int { return oldValue }) != m {
+		t.Error("Upsert returning the old slice returned a new map")
+	}
+	if m.ComputeIfAbsent(val(7), func(key string) []int { return nil }) != m {
+		t.Error("ComputeIfAbsent for a present key returned a new map")
+	}
+	copied := m.Upsert(val(7), func(oldValue []int, present bool) []int { return slices.Clone(oldValue) })
+	if copied == m {
+		t.Error("Upsert returning a copy of the slice returned the same map")
+	}
+	copied.checkInvariants(createReporter(t))
+	if shorter := m.Upsert(val(7), func(oldValue []int, present bool) []int { return oldValue[:0] }); shorter == m || len(shorter.Get(val(7))) != 0 {
+		t.Error("Upsert returning a shorter slice was ignored")
+	}
+
+	type holder struct {
+		name   string
+		values []int
+	}
+	h := NewMap[string, holder](typedStringHash, typedStringEquals).Assign("a", holder{name: "a", values: []int{1}})
+	if h.Upsert("a", func(oldValue holder, present bool) holder { return oldValue }) != h {
+		t.Error("Upsert returning the old struct returned a new map")
+	}
+	if h.Upsert("a", func(oldValue holder, present bool) holder { return holder{name: "b", values: oldValue.values} }) == h {
+		t.Error("Upsert returning a different struct returned the same map")
+	}
+}
+
+func TestMapMerge(t *testing.T) {
+	base := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 0; i < 1000; i++ {
+		base = base.Assign(val(i), i)
+	}
+	left, right := base, base
+	for i := 0; i < 1000; i += 3 {
+		left = left.Assign(val(i), -i)
+	}
+	for i := 0; i < 1000; i += 5 {
+		right = right.Assign(val(i), i*10)
+	}
+	for i := 1000; i < 1100; i++ {
+		right = right.Assign(val(i), i)
+	}
+	left = left.Delete(val(1))
+	right = right.Delete(val(2))
+
+	// rebuild both maps from scratch so the merge cannot use their shared history
+	unrelated := func(m Map[string, int]) Map[string, int] {
+		answer := NewMap[string, int](typedStringHash, typedStringEquals)
+		m.ForEach(func(key string, value int) {
+			answer = answer.Assign(key, value)
+		})
+		return answer
+	}
+
+	verify := func(actual Map[string, int], expected func(i int) (int, bool)) {
+		actual.checkInvariants(createReporter(t))
+		size := 0
+		for i := 0; i < 1100; i++ {
+			value, present := expected(i)
+			if present {
+				size++
+			}
+			if v, ok := actual.Lookup(val(i)); ok != present || v != value {
+				t.Error(fmt.Sprintf("merge mismatch: key=%d expected=%v,%v actual=%v,%v", i, value, present, v, ok))
+			}
+		}
+		if actual.Size() != size {
+			t.Error(fmt.Sprintf("merge size mismatch: expected=%d actual=%d", size, actual.Size()))
+		}
+	}
+	lookup := func(m Map[string, int], i int) (int, bool) {
+		return m.Lookup(val(i))
+	}
+
+	for _, r := range []Map[string, int]{right, unrelated(right)} {
+		verify(left.Merge(r, func(key string, a int, b int) int {
+			return a + b
+		}), func(i int) (int, bool) {
+			a, aok := lookup(left, i)
+			b, bok := lookup(right, i)
+			return a + b, aok || bok
+		})
+		verify(left.MergeWith(r, MergePreferLeft), func(i int) (int, bool) {
+			if a, ok := lookup(left, i); ok {
+				return a, true
+			}
+			return lookup(right, i)
+		})
+		verify(left.MergeWith(r, MergePreferRight), func(i int) (int, bool) {
+			if b, ok := lookup(right, i); ok {
+				return b, true
+			}
+			return lookup(left, i)
+		})
+		verify(left.MergeWith(r, MergeDropConflicts), func(i int) (int, bool) {
+			a, aok := lookup(left, i)
+			b, bok := lookup(right, i)
+			if aok && bok {
+				return 0, false
+			} else if aok {
+				return a, true
+			}
+			return b, bok
+		})
+	}
+
+	if left.MergeWith(base, MergePreferLeft) == left {
+		t.Error("merge with missing keys returned the receiver")
+	}
+	if left.MergeWith(left, MergePreferRight) != left {
+		t.Error("merge with itself returned a new map")
+	}
+	if size := left.MergeWith(left, MergeDropConflicts).Size(); size != 0 {
+		t.Error(fmt.Sprintf("expected empty map but got size %d", size))
+	}
+}
+
+func TestMapMergeSharesNodes(t *testing.T) {
+	m := CreateMap(numberHash, stringEquals)
+	left := m.Assign(keyForPath([]int{1, 1}), 1)
+	right := m.Assign(keyForPath([]int{2, 1}), 2).Assign(keyForPath([]int{2, 2}), 3)
+	merged := left.Merge(right, func(key Object, a Object, b Object) Object {
+		return a
+	})
+	merged.checkInvariants(createReporter(t))
+	if merged.(*mapImpl[Object, Object]).root.getChild(2) != right.(*mapImpl[Object, Object]).root.getChild(2) {
+		t.Error("merge did not share subtree found only in right map")
+	}
+	if merged.(*mapImpl[Object, Object]).root.getChild(1) != left.(*mapImpl[Object, Object]).root.getChild(1) {
+		t.Error("merge did not share subtree found only in left map")
+	}
+}
+
+func TestMapEqualsAndHashCode(t *testing.T) {
+	intEquals := func(a int, b int) bool {
+		return a == b
+	}
+	intHash := func(a int) HashCode {
+		return HashCode(a)
+	}
+
+	a := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 0; i < 300; i++ {
+		a = a.Assign(val(i), i)
+	}
+	b := a.Assign(val(7), 70).Assign(val(7), 7)
+	c := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 299; i >= 0; i-- {
+		c = c.Assign(val(i), i)
+	}
+
+	for _, other := range []Map[string, int]{a, b, c} {
+		if !a.Equals(other, intEquals) || !other.Equals(a, intEquals) {
+			t.Error("Equals returned false for equal maps")
+		}
+		if a.HashCode(intHash) != other.HashCode(intHash) {
+			t.Error("HashCode differs for equal maps")
+		}
+	}
+
+	for _, other := range []Map[string, int]{a.Assign(val(7), 8), a.Delete(val(7)), a.Assign("x", 1), c.Assign(val(7), 8)} {
+		if a.Equals(other, intEquals) || other.Equals(a, intEquals) {
+			t.Error("Equals returned true for different maps")
+		}
+	}
+
+	cache := NewMap[Map[string, int], string](MapHashFunc[string, int](intHash), MapEqualsFunc[string, int](intEquals))
+	cache = cache.Assign(a, "a")
+	if v := cache.Get(c); v != "a" {
+		t.Error(fmt.Sprintf("expected a but got %v", v))
+	}
+	cache = cache.Assign(b, "b")
+	cache.checkInvariants(createReporter(t))
+	if cache.Size() != 1 || cache.Get(a) != "b" {
+		t.Error(fmt.Sprintf("map keyed by maps mismatch: size=%d value=%v", cache.Size(), cache.Get(a)))
+	}
+}
+
+func TestMapContentHash(t *testing.T) {
+	intEquals := func(a int, b int) bool {
+		return a == b
+	}
+	intHash := comparableHash[int]()
+	a := NewMapWithValueHash[string, int](typedStringHash, typedStringEquals, intHash)
+	plain := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 0; i < 1000; i++ {
+		a = a.Assign(val(i), i)
+		plain = plain.Assign(val(i), i)
+	}
+	b := a.Delete(val(500)).Assign("extra", 500)
+	c := a.Assign(val(500), -1)
+	a.checkInvariants(createReporter(t))
+	b.checkInvariants(createReporter(t))
+	c.checkInvariants(createReporter(t))
+
+	if a.HashCode(nil) != plain.HashCode(intHash) {
+		t.Error(fmt.Sprintf("stored hash differs from computed hash: stored=%d computed=%d", a.HashCode(nil), plain.HashCode(intHash)))
+	}
+	if a.HashCode(nil) == b.HashCode(nil) {
+		t.Error("hash did not change when a key was replaced")
+	}
+	if a.HashCode(nil) == c.HashCode(nil) {
+		t.Error("hash did not change when a value was replaced")
+	}
+	failEquals := func(a int, b int) bool {
+		t.Error("values compared for maps with different hashes")
+		return a == b
+	}
+	if a.Equals(b, failEquals) || a.Equals(c, failEquals) {
+		t.Error("Equals returned true for different maps")
+	}
+	if !c.Assign(val(500), 500).Equals(a, intEquals) {
+		t.Error("Equals returned false for equal maps")
+	}
+	if a.Keys().HashCode() != plain.Keys().HashCode() {
+		t.Error("key set hash depends on values")
+	}
+	cache := NewMap[Map[string, int], string](MapHashFunc[string, int](nil), MapEqualsFunc[string, int](intEquals))
+	cache = cache.Assign(a, "a").Assign(c, "c")
+	cache.checkInvariants(createReporter(t))
+	if cache.Size() != 2 || cache.Get(a) != "a" || cache.Get(c) != "c" {
+		t.Error(fmt.Sprintf("map keyed by stored hash mismatch: size=%d", cache.Size()))
+	}
+
+	updated := a.Update(val(500), func(_ int, _ bool) (int, bool) {
+		return -1, true
+	})
+	updated.checkInvariants(createReporter(t))
+	doubled := a.MapValues(func(_ string, value int) int {
+		return value * 2
+	})
+	doubled.checkInvariants(createReporter(t))
+	merged := b.Merge(c, func(_ string, left int, right int) int {
+		return left + right
+	})
+	merged.checkInvariants(createReporter(t))
+	if updated.HashCode(nil) != c.HashCode(nil) || merged.Size() != 1001 {
+		t.Error("derived maps have inconsistent hashes")
+	}
+
+	builder := a.ToBuilder()
+	builder.Delete(val(500))
+	builder.Assign("extra", 500)
+	built := builder.Build()
+	built.checkInvariants(createReporter(t))
+	if built.HashCode(nil) != b.HashCode(nil) || !built.Equals(b, intEquals) {
+		t.Error("builder produced a map with a different hash")
+	}
+}
+
+func TestSetHashCode(t *testing.T) {
+	a := NewSet[string](typedStringHash, typedStringEquals)
+	b := NewSet[string](typedStringHash, typedStringEquals)
+	for i := 0; i < 100; i++ {
+		a = a.Add(val(i))
+		b = b.Add(val(99 - i))
+	}
+	if a.HashCode() != b.HashCode() {
+		t.Error("HashCode differs for equal sets")
+	}
+
+	sets := CreateSet(ObjectHashFunc(SetHashFunc[string]()), ObjectEqualsFunc(SetEqualsFunc[string]()))
+	sets = sets.Add(a)
+	sets = sets.Add(b)
+	sets = sets.Add(a.Delete(val(5)))
+	sets.checkInvariants(createReporter(t))
+	if sets.Size() != 2 {
+		t.Error(fmt.Sprintf("expected 2 sets but got %d", sets.Size()))
+	}
+	if !sets.Contains(b.Delete(val(5))) {
+		t.Error("set of sets did not contain equal set")
+	}
+}
+
+func TestComparableHashCode(t *testing.T) {
+	intEquals := func(a int, b int) bool {
+		return a == b
+	}
+	intHash := func(a int) HashCode {
+		return HashCode(a)
+	}
+
+	native := make(map[string]int)
+	a := NewComparableMap[string, int]()
+	for i := 0; i < 100; i++ {
+		native[val(i)] = i
+		a = a.Assign(val(i), i)
+	}
+	b := FromGoMap(native)
+	if !a.Equals(b, intEquals) || a.HashCode(intHash) != b.HashCode(intHash) {
+		t.Error("HashCode differs for equal comparable maps")
+	}
+	cache := NewMap[Map[string, int], string](MapHashFunc[string, int](intHash), MapEqualsFunc[string, int](intEquals))
+	cache = cache.Assign(a, "a")
+	if v, ok := cache.Lookup(b); !ok || v != "a" {
+		t.Error(fmt.Sprintf("map keyed by comparable maps mismatch: value=%v ok=%v", v, ok))
+	}
+
+	values := []int{5, 3, 9, 1}
+	s := NewComparableSet[int]()
+	for _, value := range values {
+		s = s.Add(value)
+	}
+	if other := SetFromSlice(values); !s.Equals(other) || s.HashCode() != other.HashCode() {
+		t.Error("HashCode differs for equal comparable sets")
+	}
+}
+
+func TestMapTransforms(t *testing.T) {
+	m := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 0; i < 1000; i++ {
+		m = m.Assign(val(i), i)
+	}
+	even := func(key string, value int) bool {
+		return value%2 == 0
+	}
+
+	evens := m.Filter(even)
+	evens.checkInvariants(createReporter(t))
+	if evens.Size() != 500 || !evens.Every(even) {
+		t.Error(fmt.Sprintf("Filter mismatch: size=%d", evens.Size()))
+	}
+	if evens.Filter(even) != evens {
+		t.Error("Filter keeping every entry returned a new map")
+	}
+	if size := m.Filter(func(key string, value int) bool { return false }).Size(); size != 0 {
+		t.Error(fmt.Sprintf("expected empty map but got size %d", size))
+	}
+
+	matching, rest := m.Partition(even)
+	matching.checkInvariants(createReporter(t))
+	rest.checkInvariants(createReporter(t))
+	if matching.Size() != 500 || rest.Size() != 500 || !matching.Every(even) || rest.Any(even) {
+		t.Error(fmt.Sprintf("Partition mismatch: sizes=%d,%d", matching.Size(), rest.Size()))
+	}
+	if all, none := evens.Partition(even); all != evens || none.Size() != 0 {
+		t.Error("Partition with every entry matching did not return the receiver")
+	}
+
+	doubled := m.MapValues(func(key string, value int) int {
+		return value * 2
+	})
+	doubled.checkInvariants(createReporter(t))
+	if doubled.Size() != m.Size() || doubled.Get(val(21)) != 42 {
+		t.Error(fmt.Sprintf("MapValues mismatch: size=%d value=%d", doubled.Size(), doubled.Get(val(21))))
+	}
+	if m.MapValues(func(key string, value int) int { return value }) != m {
+		t.Error("MapValues returning the same values returned a new map")
+	}
+
+	keys := NewComparableSet[string]()
+	for i := 0; i < 1000; i += 100 {
+		keys = keys.Add(val(i))
+	}
+	retained := m.Retain(keys)
+	retained.checkInvariants(createReporter(t))
+	if retained.Size() != 10 || retained.Get(val(300)) != 300 {
+		t.Error(fmt.Sprintf("Retain mismatch: size=%d", retained.Size()))
+	}
+	without := m.Without(keys)
+	without.checkInvariants(createReporter(t))
+	if without.Size() != 990 || without.ContainsKey(val(300)) {
+		t.Error(fmt.Sprintf("Without mismatch: size=%d", without.Size()))
+	}
+	if m.Without(NewComparableSet[string]()) != m {
+		t.Error("Without an empty set returned a new map")
+	}
+
+	// key sets derived from the map are combined with it node by node
+	if size := m.Retain(evens.Keys()).Size(); size != 500 {
+		t.Error(fmt.Sprintf("expected 500 but got %d", size))
+	}
+	if size := m.Without(evens.Keys()).Size(); size != 500 {
+		t.Error(fmt.Sprintf("expected 500 but got %d", size))
+	}
+	if m.Retain(m.Keys()) != m {
+		t.Error("Retain of its own keys returned a new map")
+	}
+}
+
+func TestMapBatchUpdates(t *testing.T) {
+	m := CreateMap(numberHash, stringEquals)
+	var entries []Entry[Object, Object]
+	for i := 0; i < 2000; i++ {
+		entries = append(entries, Entry[Object, Object]{Key: val(i), Value: i})
+	}
+	entries = append(entries, Entry[Object, Object]{Key: val(5), Value: "five"})
+	m = m.AssignAll(entries)
+	m.checkInvariants(createReporter(t))
+	if m.Size() != 2000 {
+		t.Error(fmt.Sprintf("expected size 2000 but got %d", m.Size()))
+	}
+	verifyValue(t, m, val(5), "five")
+	verifyValue(t, m, val(1999), 1999)
+
+	if m.AssignAll(entries[1000:]) != m {
+		t.Error("AssignAll of existing entries returned a new map")
+	}
+	if m.DeleteAll([]Object{val(-1), val(5000)}) != m {
+		t.Error("DeleteAll of missing keys returned a new map")
+	}
+
+	var keys []Object
+	for i := 0; i < 2000; i += 2 {
+		keys = append(keys, val(i))
+	}
+	deleted := m.DeleteAll(keys)
+	deleted.checkInvariants(createReporter(t))
+	if deleted.Size() != 1000 || deleted.ContainsKey(val(4)) || !deleted.ContainsKey(val(5)) {
+		t.Error(fmt.Sprintf("DeleteAll mismatch: size=%d", deleted.Size()))
+	}
+
+	mixed := deleted.AssignAll([]Entry[Object, Object]{{Key: val(4), Value: 4}, {Key: val(7), Value: 70}})
+	mixed.checkInvariants(createReporter(t))
+	if mixed.Size() != 1001 {
+		t.Error(fmt.Sprintf("expected size 1001 but got %d", mixed.Size()))
+	}
+	verifyValue(t, mixed, val(7), 70)
+
+	var all []Object
+	for i := 0; i < 2000; i++ {
+		all = append(all, val(i))
+	}
+	if size := m.DeleteAll(all).Size(); size != 0 {
+		t.Error(fmt.Sprintf("expected empty map but got size %d", size))
+	}
+}
+
+func TestSetBatchUpdates(t *testing.T) {
+	values := make([]int, 0, 1500)
+	for i := 0; i < 1500; i++ {
+		values = append(values, i%1000)
+	}
+	s := NewComparableSet[int]().AddAll(values)
+	s.checkInvariants(createReporter(t))
+	if s.Size() != 1000 {
+		t.Error(fmt.Sprintf("expected size 1000 but got %d", s.Size()))
+	}
+	if s.AddAll(values[:10]) != s {
+		t.Error("AddAll of existing values returned a new set")
+	}
+
+	trimmed := s.DeleteAll(values[500:1000])
+	trimmed.checkInvariants(createReporter(t))
+	if trimmed.Size() != 500 || trimmed.Contains(700) || !trimmed.Contains(100) {
+		t.Error(fmt.Sprintf("DeleteAll mismatch: size=%d", trimmed.Size()))
+	}
+	if !trimmed.Equals(s.Filter(func(v int) bool { return v < 500 })) {
+		t.Error("DeleteAll result differs from filtered set")
+	}
+}
+
 func TestMapPaths(t *testing.T) {
 	m := CreateMap(numberHash, stringEquals)
 
@@ -271,7 +934,7 @@ func createReporter(t *testing.T) reporter {
 	}
 }
 
-func verifyValue(t *testing.T, m Map, key Object, expected Object) {
+func verifyValue(t *testing.T, m Map[Object, Object], key Object, expected Object) {
 	actual := m.Get(key)
 	if actual != expected {
 		t.Error(fmt.Sprintf("Get mismatch: key=%v expected=%v actual=%v", key, expected, actual))
@@ -342,6 +1005,169 @@ func TestSetIterator(t *testing.T) {
 	}
 }
 
+func TestRangeIterators(t *testing.T) {
+	m := CreateMap(numberHash, stringEquals)
+	m = m.Assign(keyForPath([]int{0}), 0)
+	m = m.Assign(keyForPath([]int{1}), 1)
+	m = m.Assign(keyForPath([]int{1, 1}), 11)
+	m = m.Assign(keyForPath([]int{1, 2}), 12)
+	m = m.Assign(keyForPath([]int{2, 3, 3}), 233)
+
+	actual := "|"
+	for key, value := range m.All() {
+		actual += fmt.Sprintf("%v=%v|", key, value)
+	}
+	assertString(actual, "|0=0|1=1|33=11|65=12|3170=233|", t)
+
+	actual = "|"
+	for key := range m.KeySeq() {
+		actual += fmt.Sprintf("%v|", key)
+	}
+	assertString(actual, "|0|1|33|65|3170|", t)
+
+	actual = "|"
+	for value := range m.ValueSeq() {
+		actual += fmt.Sprintf("%v|", value)
+	}
+	assertString(actual, "|0|1|11|12|233|", t)
+
+	actual = "|"
+	for value := range m.Keys().All() {
+		actual += fmt.Sprintf("%v|", value)
+		if value == "33" {
+			break
+		}
+	}
+	assertString(actual, "|0|1|33|", t)
+
+	count := 0
+	for range m.All() {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Error(fmt.Sprintf("expected loop to stop after 2 entries but got %d", count))
+	}
+
+	for range CreateSet(numberHash, stringEquals).All() {
+		t.Error("range over empty set produced a value")
+	}
+}
+
+func TestMapSearch(t *testing.T) {
+	m := NewMap[string, int](typedStringHash, typedStringEquals)
+	for i := 0; i < 1000; i++ {
+		m = m.Assign(val(i), i)
+	}
+
+	visits := 0
+	complete := m.ForEachWhile(func(key string, value int) bool {
+		visits++
+		return visits < 10
+	})
+	if complete || visits != 10 {
+		t.Error(fmt.Sprintf("ForEachWhile did not stop: complete=%v visits=%d", complete, visits))
+	}
+	if !m.ForEachWhile(func(key string, value int) bool { return true }) {
+		t.Error("ForEachWhile reported early stop")
+	}
+
+	visits = 0
+	key, value, found := m.Find(func(key string, value int) bool {
+		visits++
+		return value == 123
+	})
+	if !found || key != "123" || value != 123 || visits > m.Size() {
+		t.Error(fmt.Sprintf("Find mismatch: key=%v value=%v found=%v", key, value, found))
+	}
+	if _, _, found := m.Find(func(key string, value int) bool { return value < 0 }); found {
+		t.Error("Find returned true for missing entry")
+	}
+
+	if !m.Any(func(key string, value int) bool { return value == 999 }) || m.Any(func(key string, value int) bool { return value > 999 }) {
+		t.Error("Any returned incorrect result")
+	}
+	if !m.Every(func(key string, value int) bool { return key == val(value) }) || m.Every(func(key string, value int) bool { return value < 999 }) {
+		t.Error("Every returned incorrect result")
+	}
+
+	sum := FoldMap(m, 0, func(acc int, key string, value int) int {
+		return acc + value
+	})
+	if sum != 499500 {
+		t.Error(fmt.Sprintf("expected 499500 but got %d", sum))
+	}
+}
+
+func TestSetSearch(t *testing.T) {
+	s := NewComparableSet[int]()
+	for i := 0; i < 100; i++ {
+		s = s.Add(i)
+	}
+
+	visits := 0
+	if s.ForEachWhile(func(v int) bool { visits++; return false }) || visits != 1 {
+		t.Error(fmt.Sprintf("ForEachWhile did not stop: visits=%d", visits))
+	}
+	if v, found := s.Find(func(v int) bool { return v*v == 49 }); !found || v != 7 {
+		t.Error(fmt.Sprintf("Find mismatch: value=%v found=%v", v, found))
+	}
+	if !s.Any(func(v int) bool { return v == 50 }) || s.Any(func(v int) bool { return v == 100 }) {
+		t.Error("Any returned incorrect result")
+	}
+	if !s.Every(func(v int) bool { return v < 100 }) || s.Every(func(v int) bool { return v%2 == 0 }) {
+		t.Error("Every returned incorrect result")
+	}
+	if !NewComparableSet[int]().Every(func(v int) bool { return false }) {
+		t.Error("Every returned false for empty set")
+	}
+	if count := FoldSet(s, 0, func(acc int, v int) int { return acc + v%2 }); count != 50 {
+		t.Error(fmt.Sprintf("expected 50 but got %d", count))
+	}
+}
+
+func TestMapViews(t *testing.T) {
+	m := CreateMap(numberHash, stringEquals)
+	m = m.Assign(keyForPath([]int{0}), 0)
+	m = m.Assign(keyForPath([]int{1}), 1)
+	m = m.Assign(keyForPath([]int{1, 1}), 11)
+	m = m.Assign(keyForPath([]int{2, 3, 3}), 233)
+
+	values := m.Values()
+	if values.Size() != 4 {
+		t.Error(fmt.Sprintf("expected 4 values but got %d", values.Size()))
+	}
+	actual := "|"
+	for i := values.Iterate(); i.Next(); {
+		actual += fmt.Sprintf("%v|", i.Get())
+	}
+	assertString(actual, "|0|1|11|233|", t)
+	assertString(fmt.Sprint(values.ToSlice()), "[0 1 11 233]", t)
+
+	actual = "|"
+	for entry := range m.Entries().All() {
+		actual += fmt.Sprintf("%v=%v|", entry.Key, entry.Value)
+		if entry.Value == 11 {
+			break
+		}
+	}
+	assertString(actual, "|0=0|1=1|33=11|", t)
+
+	actual = "|"
+	m.Entries().ForEach(func(entry Entry[Object, Object]) {
+		actual += fmt.Sprintf("%v=%v|", entry.Key, entry.Value)
+	})
+	assertString(actual, "|0=0|1=1|33=11|3170=233|", t)
+	assertString(fmt.Sprint(m.Entries().ToSlice()), "[{0 0} {1 1} {33 11} {3170 233}]", t)
+	assertString(fmt.Sprint(m.Keys().ToSlice()), "[0 1 33 3170]", t)
+
+	if i := m.Delete(keyForPath([]int{0})).Delete(keyForPath([]int{1})).Delete(keyForPath([]int{1, 1})).Delete(keyForPath([]int{2, 3, 3})).Values().Iterate(); i.Next() {
+		t.Error("Next() method on empty values iterator returned true")
+	}
+}
+
 func TestSetIntersection(t *testing.T) {
 	a := CreateSet(numberHash, stringEquals)
 	a = a.Add(val(0))
@@ -384,6 +1210,218 @@ func TestSetUnion(t *testing.T) {
 	assertString(sortedSetString(a.Union(a)), "|0|4|", t)
 }
 
+func TestSetAlgebraSharedHistory(t *testing.T) {
+	base := CreateSet(stringHash, stringEquals)
+	for i := 0; i < 2000; i++ {
+		base = base.Add(val(i))
+	}
+	a, b := base, base
+	for i := 0; i < 2000; i += 3 {
+		a = a.Delete(val(i))
+	}
+	for i := 0; i < 2000; i += 7 {
+		b = b.Delete(val(i))
+	}
+	for i := 2000; i < 2100; i++ {
+		a = a.Add(val(i))
+	}
+	for i := 2050; i < 2200; i++ {
+		b = b.Add(val(i))
+	}
+
+	// sets with the same contents that do not share a trie layout
+	unrelated := func(s Set[Object]) Set[Object] {
+		answer := CreateSet(stringHash, stringEquals)
+		s.ForEach(func(v Object) {
+			answer = answer.Add(v)
+		})
+		return answer
+	}
+
+	check := func(name string, structural Set[Object], expected Set[Object]) {
+		structural.checkInvariants(createReporter(t))
+		assertString(sortedSetString(structural), sortedSetString(expected), t)
+		if structural.Size() != expected.Size() {
+			t.Error(fmt.Sprintf("%s size mismatch: expected=%d actual=%d", name, expected.Size(), structural.Size()))
+		}
+	}
+	check("union", a.Union(b), unrelated(a).Union(unrelated(b)))
+	check("intersection", a.Intersection(b), unrelated(a).Intersection(unrelated(b)))
+	check("difference", a.Difference(b), unrelated(a).Difference(unrelated(b)))
+	check("difference", b.Difference(a), unrelated(b).Difference(unrelated(a)))
+	check("symmetric difference", a.SymmetricDifference(b), unrelated(a).SymmetricDifference(unrelated(b)))
+
+	if a.Union(a) != a || a.Intersection(a) != a {
+		t.Error("combining a set with itself returned a new set")
+	}
+	if a.Difference(a).Size() != 0 || a.SymmetricDifference(a).Size() != 0 {
+		t.Error("difference of a set with itself was not empty")
+	}
+	if a.Union(base.Intersection(a)) != a {
+		t.Error("union with a subset returned a new set")
+	}
+}
+
+func TestSetAlgebraSkipsSharedSubtrees(t *testing.T) {
+	base := CreateSet(stringHash, stringEquals)
+	for i := 0; i < 2000; i++ {
+		base = base.Add(val(i))
+	}
+	a := base.Add(val(5000))
+
+	// replace the descendants of every subtree shared by both sets with nil so that
+	// walking a shared subtree to count its keys panics
+	baseRoot, aRoot := base.(*setImpl[Object, struct{}]).root, a.(*setImpl[Object, struct{}]).root
+	poisoned := 0
+	for i, child := range aRoot.children {
+		if child == baseRoot.children[i] && child.children != nil {
+			child.children = make([]*node[Object, struct{}], len(child.children))
+			poisoned++
+		}
+	}
+	if poisoned == 0 {
+		t.Fatal("no shared subtrees to poison")
+	}
+
+	if diff := a.Difference(base); diff.Size() != 1 || !diff.Contains(val(5000)) {
+		t.Error(fmt.Sprintf("difference mismatch: size=%d", diff.Size()))
+	}
+	if diff := base.Difference(a); diff.Size() != 0 {
+		t.Error(fmt.Sprintf("difference mismatch: size=%d", diff.Size()))
+	}
+	if diff := a.SymmetricDifference(base); diff.Size() != 1 || !diff.Contains(val(5000)) {
+		t.Error(fmt.Sprintf("symmetric difference mismatch: size=%d", diff.Size()))
+	}
+	if a.Union(base) != a || base.Intersection(a) != base {
+		t.Error("unchanged set was copied")
+	}
+	if intersection := a.Intersection(base); intersection.Size() != base.Size() || intersection.Contains(val(5000)) {
+		t.Error(fmt.Sprintf("intersection mismatch: size=%d", intersection.Size()))
+	}
+}
+
+func TestSetDifference(t *testing.T) {
+	a := CreateSet(numberHash, stringEquals)
+	a = a.Add(val(0))
+	a = a.Add(val(1))
+	a = a.Add(val(2))
+	b := CreateSet(numberHash, stringEquals)
+	b = b.Add(val(1))
+	b = b.Add(val(3))
+	assertString(sortedSetString(a.Difference(b)), "|0|2|", t)
+	assertString(sortedSetString(b.Difference(a)), "|3|", t)
+	assertString(sortedSetString(a.SymmetricDifference(b)), "|0|2|3|", t)
+	assertString(sortedSetString(b.SymmetricDifference(a)), "|0|2|3|", t)
+	assertString(sortedSetString(a.Difference(a)), "|", t)
+}
+
+func TestSetPredicates(t *testing.T) {
+	base := CreateSet(stringHash, stringEquals)
+	for i := 0; i < 500; i++ {
+		base = base.Add(val(i))
+	}
+	evens, odds := base, base
+	for i := 0; i < 500; i++ {
+		if i%2 == 0 {
+			odds = odds.Delete(val(i))
+		} else {
+			evens = evens.Delete(val(i))
+		}
+	}
+	fewerEvens := evens.Delete(val(10))
+
+	unrelated := func(s Set[Object]) Set[Object] {
+		answer := CreateSet(numberHash, stringEquals)
+		s.ForEach(func(v Object) {
+			answer = answer.Add(v)
+		})
+		return answer
+	}
+
+	for _, other := range []Set[Object]{evens, unrelated(evens)} {
+		other.checkInvariants(createReporter(t))
+		if !fewerEvens.IsSubsetOf(other) || other.IsSubsetOf(fewerEvens) {
+			t.Error("IsSubsetOf returned incorrect result")
+		}
+		if !other.IsSupersetOf(fewerEvens) || fewerEvens.IsSupersetOf(other) {
+			t.Error("IsSupersetOf returned incorrect result")
+		}
+		if !other.IsSubsetOf(base) || other.IsSupersetOf(base) {
+			t.Error("IsSubsetOf returned incorrect result for base set")
+		}
+		if !odds.IsDisjoint(other) || !other.IsDisjoint(odds) || base.IsDisjoint(other) {
+			t.Error("IsDisjoint returned incorrect result")
+		}
+		if !evens.Equals(other) || !other.Equals(evens) || fewerEvens.Equals(other) || odds.Equals(other) {
+			t.Error("Equals returned incorrect result")
+		}
+	}
+
+	empty := CreateSet(stringHash, stringEquals)
+	if !empty.IsSubsetOf(odds) || !empty.IsDisjoint(odds) || empty.Equals(odds) {
+		t.Error("empty set predicates returned incorrect result")
+	}
+}
+
+func TestSetTransforms(t *testing.T) {
+	s := NewComparableSet[int]()
+	for i := 0; i < 1000; i++ {
+		s = s.Add(i)
+	}
+	even := func(v int) bool {
+		return v%2 == 0
+	}
+
+	evens := s.Filter(even)
+	evens.checkInvariants(createReporter(t))
+	if evens.Size() != 500 || !evens.Every(even) {
+		t.Error(fmt.Sprintf("Filter mismatch: size=%d", evens.Size()))
+	}
+	if evens.Filter(even) != evens {
+		t.Error("Filter keeping every value returned a new set")
+	}
+
+	matching, rest := s.Partition(even)
+	matching.checkInvariants(createReporter(t))
+	rest.checkInvariants(createReporter(t))
+	if !matching.Equals(evens) || rest.Size() != 500 || rest.Any(even) {
+		t.Error(fmt.Sprintf("Partition mismatch: sizes=%d,%d", matching.Size(), rest.Size()))
+	}
+	if !matching.Union(rest).Equals(s) || !matching.IsDisjoint(rest) {
+		t.Error("Partition results do not combine into the original set")
+	}
+
+	strings := MapTo(s, func(v int) string {
+		return val(v % 10)
+	}, typedStringHash, typedStringEquals)
+	strings.checkInvariants(createReporter(t))
+	if strings.Size() != 10 || !strings.Contains("7") {
+		t.Error(fmt.Sprintf("MapTo mismatch: size=%d", strings.Size()))
+	}
+
+	groups := GroupBy(s, func(v int) string {
+		return val(v % 3)
+	}, typedStringHash, typedStringEquals)
+	groups.checkInvariants(createReporter(t))
+	if groups.Size() != 3 {
+		t.Error(fmt.Sprintf("expected 3 groups but got %d", groups.Size()))
+	}
+	total := 0
+	groups.ForEach(func(key string, group Set[int]) {
+		group.checkInvariants(createReporter(t))
+		if !group.Every(func(v int) bool { return val(v%3) == key }) {
+			t.Error(fmt.Sprintf("group %s contains a value with a different key", key))
+		}
+		total += group.Size()
+	})
+	if total != s.Size() {
+		t.Error(fmt.Sprintf("expected %d grouped values but got %d", s.Size(), total))
+	}
+	if !groups.Get("0").Union(groups.Get("1")).Union(groups.Get("2")).Equals(s) {
+		t.Error("groups do not combine into the original set")
+	}
+}
+
 func assertString(actual string, expected string, t *testing.T) {
 	if actual != expected {
 		t.Error(fmt.Sprintf("mismatch: expected(%s) actual(%s)", expected, actual))
@@ -398,7 +1436,7 @@ func keyForPath(indexes []int) string {
 	return val(key)
 }
 
-func sortedSetString(s Set) string {
+func sortedSetString(s Set[Object]) string {
 	answer := "|"
 	for _, v := range sortedSetValues(s) {
 		answer += fmt.Sprintf("%v|", v)
@@ -406,7 +1444,7 @@ func sortedSetString(s Set) string {
 	return answer
 }
 
-func sortedSetValues(s Set) []string {
+func sortedSetValues(s Set[Object]) []string {
 	answer := make([]string, s.Size())
 	i := 0
 	s.ForEach(func(v Object) {
@@ -0,0 +1,203 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// Queue is an immutable first in first out sequence.  Values are pushed onto the
+// back and popped from the front.  Iteration visits values from front to back.
+// Push and Pop take amortized constant time even when older versions of the queue
+// are pushed or popped again.
+type Queue[T any] interface {
+	Push(value T) Queue[T]
+	Pop() (Queue[T], T, bool)
+	Peek() (T, bool)
+	Size() int
+	Iterate() Iterator[T]
+	ForEach(v SetVisitor[T])
+	All() iter.Seq[T]
+	ToSlice() []T
+	Equals(other Queue[T], equals EqualsFunc[T]) bool
+	checkInvariants(report reporter)
+}
+
+// queueImpl is a banker's queue.  front is a lazy stream holding frontSize values
+// in the order they will be popped and back holds the remaining values in reverse
+// order.  back never holds more values than front.  When a push or pop would break
+// that rule the reversal of back is appended to front as a suspension that is only
+// evaluated once the values before it have been popped.  Every stream cell is
+// evaluated at most once no matter how many versions of the queue share it.
+type queueImpl[T any] struct {
+	front     *stream[T]
+	frontSize int
+	back      *listNode[T]
+}
+
+// stream is a persistent singly linked list whose cells are computed on first use.
+// A nil stream is empty and so is a stream whose thunk returns nil.
+type stream[T any] struct {
+	once  sync.Once
+	thunk func() *streamCell[T]
+	cell  *streamCell[T]
+}
+
+type streamCell[T any] struct {
+	value T
+	next  *stream[T]
+}
+
+// queueIteratorImpl visits the values of current followed by the values of
+// reversed in reverse order.  reversed is copied into remaining in the correct order
+// once current is exhausted.
+type queueIteratorImpl[T any] struct {
+	current   *stream[T]
+	reversed  *listNode[T]
+	remaining *listNode[T]
+	value     T
+}
+
+// CreateQueue creates an empty Queue.
+func CreateQueue[T any]() Queue[T] {
+	return &queueImpl[T]{}
+}
+
+func newQueue[T any](front *stream[T], frontSize int, back *listNode[T]) *queueImpl[T] {
+	if listSize(back) > frontSize {
+		return &queueImpl[T]{front: front.appendReversed(back), frontSize: frontSize + back.size}
+	}
+	return &queueImpl[T]{front: front, frontSize: frontSize, back: back}
+}
+
+// force returns the first cell of the stream, computing it if necessary, or nil if
+// the stream is empty.
+func (this *stream[T]) force() *streamCell[T] {
+	if this == nil {
+		return nil
+	}
+	this.once.Do(func() {
+		if this.thunk != nil {
+			this.cell = this.thunk()
+			this.thunk = nil
+		}
+	})
+	return this.cell
+}
+
+// appendReversed returns a stream holding the values of this stream followed by
+// the values of list in reverse order.  list is only reversed once every value of
+// this stream has been forced.
+func (this *stream[T]) appendReversed(list *listNode[T]) *stream[T] {
+	return &stream[T]{thunk: func() *streamCell[T] {
+		if cell := this.force(); cell != nil {
+			return &streamCell[T]{value: cell.value, next: cell.next.appendReversed(list)}
+		}
+		var answer *stream[T]
+		for n := list; n != nil; n = n.next {
+			answer = &stream[T]{cell: &streamCell[T]{value: n.value, next: answer}}
+		}
+		return answer.force()
+	}}
+}
+
+func (this *queueImpl[T]) Push(value T) Queue[T] {
+	return newQueue(this.front, this.frontSize, this.back.push(value))
+}
+
+// Pop returns a queue without its front value along with that value.  The final
+// result is false if the queue is empty.
+func (this *queueImpl[T]) Pop() (Queue[T], T, bool) {
+	cell := this.front.force()
+	if cell == nil {
+		var zero T
+		return this, zero, false
+	}
+	return newQueue(cell.next, this.frontSize-1, this.back), cell.value, true
+}
+
+// Peek returns the front value.  The second result is false if the queue is empty.
+func (this *queueImpl[T]) Peek() (T, bool) {
+	cell := this.front.force()
+	if cell == nil {
+		var zero T
+		return zero, false
+	}
+	return cell.value, true
+}
+
+func (this *queueImpl[T]) Size() int {
+	return this.frontSize + listSize(this.back)
+}
+
+func (this *queueImpl[T]) Iterate() Iterator[T] {
+	return &queueIteratorImpl[T]{current: this.front, reversed: this.back}
+}
+
+func (this *queueImpl[T]) ForEach(v SetVisitor[T]) {
+	for i := this.Iterate(); i.Next(); {
+		v(i.Get())
+	}
+}
+
+// All returns an iterator over the values of the queue from front to back for use
+// with range.
+func (this *queueImpl[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *queueImpl[T]) ToSlice() []T {
+	answer := make([]T, 0, this.Size())
+	for i := this.Iterate(); i.Next(); {
+		answer = append(answer, i.Get())
+	}
+	return answer
+}
+
+// Equals returns true if other holds the same number of values in the same order
+// and equals returns true for each pair of values.
+func (this *queueImpl[T]) Equals(other Queue[T], equals EqualsFunc[T]) bool {
+	return this.Size() == other.Size() && listsEqual(this.Iterate(), other.Iterate(), equals)
+}
+
+func (this *queueIteratorImpl[T]) Next() bool {
+	if cell := this.current.force(); cell != nil {
+		this.value = cell.value
+		this.current = cell.next
+		return true
+	}
+	if this.reversed != nil {
+		this.remaining = this.reversed.reverseOnto(nil)
+		this.reversed = nil
+	}
+	if this.remaining == nil {
+		return false
+	}
+	this.value = this.remaining.value
+	this.remaining = this.remaining.next
+	return true
+}
+
+func (this *queueIteratorImpl[T]) Get() T {
+	return this.value
+}
+
+func (this *queueImpl[T]) checkInvariants(report reporter) {
+	this.back.checkInvariants(report)
+	frontSize := 0
+	for cell := this.front.force(); cell != nil; cell = cell.next.force() {
+		frontSize++
+	}
+	if frontSize != this.frontSize {
+		report(fmt.Sprintf("front size mismatch: expected=%d actual=%d", frontSize, this.frontSize))
+	}
+	if listSize(this.back) > this.frontSize {
+		report(fmt.Sprintf("back larger than front: front=%d back=%d", this.frontSize, listSize(this.back)))
+	}
+}
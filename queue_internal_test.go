@@ -0,0 +1,85 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	q := CreateQueue[int]()
+	var expected []int
+	random := rand.New(rand.NewSource(22))
+	for i := 0; i < 5000; i++ {
+		if random.Intn(3) == 0 {
+			var value int
+			var ok bool
+			q, value, ok = q.Pop()
+			if len(expected) == 0 {
+				if ok {
+					t.Error("Pop returned true for empty queue")
+				}
+			} else if !ok || value != expected[0] {
+				t.Error(fmt.Sprintf("Pop mismatch: expected=%d actual=%d ok=%v", expected[0], value, ok))
+			} else {
+				expected = expected[1:]
+			}
+		} else {
+			q = q.Push(i)
+			expected = append(expected, i)
+		}
+		if i%500 == 0 {
+			q.checkInvariants(createReporter(t))
+			if !slices.Equal(q.ToSlice(), expected) {
+				t.Error(fmt.Sprintf("ToSlice mismatch at %d", i))
+			}
+		}
+	}
+	q.checkInvariants(createReporter(t))
+	if q.Size() != len(expected) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(expected), q.Size()))
+	}
+	verifyRangedTwice(t, q.All(), expected)
+	if front, ok := q.Peek(); len(expected) > 0 && (!ok || front != expected[0]) {
+		t.Error(fmt.Sprintf("Peek mismatch: expected=%d actual=%d", expected[0], front))
+	}
+
+	equals := func(a int, b int) bool { return a == b }
+	other := CreateQueue[int]()
+	for _, value := range expected {
+		other = other.Push(value)
+	}
+	if !q.Equals(other, equals) || q.Equals(other.Push(1), equals) {
+		t.Error("Equals returned incorrect result")
+	}
+}
+
+func TestQueueOldVersions(t *testing.T) {
+	q := CreateQueue[int]()
+	for i := 0; i < 1000; i++ {
+		q = q.Push(i)
+	}
+	versions := []Queue[int]{q}
+	for i := 0; i < 600; i++ {
+		q, _, _ = q.Pop()
+		versions = append(versions, q)
+	}
+
+	// popping an older version again reuses the stream cells evaluated the first time
+	first, _, _ := versions[300].Pop()
+	second, _, _ := versions[300].Pop()
+	if first.(*queueImpl[int]).front.force() != second.(*queueImpl[int]).front.force() {
+		t.Error("popping the same version twice evaluated the front again")
+	}
+	for i, version := range versions {
+		version.checkInvariants(createReporter(t))
+		if front, ok := version.Peek(); !ok || front != i || version.Size() != 1000-i {
+			t.Error(fmt.Sprintf("old version mismatch: index=%d front=%d size=%d", i, front, version.Size()))
+		}
+		pushed := version.Push(-1)
+		if values := pushed.ToSlice(); len(values) != 1001-i || values[0] != i || values[len(values)-1] != -1 {
+			t.Error(fmt.Sprintf("push onto old version mismatch: index=%d", i))
+		}
+	}
+}
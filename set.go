@@ -1,105 +1,290 @@
 package immutableMap
 
-import "fmt"
+import (
+	"fmt"
+	"iter"
+)
 
-type Set interface {
-	Add(key Object) Set
-	Delete(key Object) Set
-	Contains(key Object) bool
+type Set[T any] interface {
+	Add(key T) Set[T]
+	Delete(key T) Set[T]
+	AddAll(values []T) Set[T]
+	DeleteAll(values []T) Set[T]
+	Contains(key T) bool
 	Size() int
-	Iterate() SetIterator
-	ForEach(v SetVisitor)
-	Union(s Set) Set
-	Intersection(s Set) Set
+	Iterate() SetIterator[T]
+	ForEach(v SetVisitor[T])
+	ForEachWhile(v SetPredicate[T]) bool
+	Find(p SetPredicate[T]) (T, bool)
+	Any(p SetPredicate[T]) bool
+	Every(p SetPredicate[T]) bool
+	All() iter.Seq[T]
+	ToSlice() []T
+	Union(s Set[T]) Set[T]
+	Intersection(s Set[T]) Set[T]
+	Difference(s Set[T]) Set[T]
+	SymmetricDifference(s Set[T]) Set[T]
+	IsSubsetOf(s Set[T]) bool
+	IsSupersetOf(s Set[T]) bool
+	IsDisjoint(s Set[T]) bool
+	Equals(s Set[T]) bool
+	HashCode() HashCode
+	Filter(p SetPredicate[T]) Set[T]
+	Partition(p SetPredicate[T]) (Set[T], Set[T])
+	ToBuilder() SetBuilder[T]
+	emptyBuilder() SetBuilder[T]
 	checkInvariants(report reporter)
 }
 
-type SetIterator interface {
+type SetIterator[T any] interface {
 	Next() bool
-	Get() Object
+	Get() T
 }
 
-type SetVisitor func(Object)
+type SetVisitor[T any] func(T)
+type SetPredicate[T any] func(T) bool
 
-type setImpl struct {
-	hash   HashFunc
-	equals EqualsFunc
-	root   *node
+// setImpl stores its values as the keys of a trie.  V is the value type of that
+// trie: struct{} for ordinary sets or the value type of the map for key views.
+type setImpl[T any, V any] struct {
+	hash   HashFunc[T]
+	equals EqualsFunc[T]
+	layout *layoutToken
+	root   *node[T, V]
 	size   int
 }
 
-type setIteratorImpl struct {
-	state *iteratorState
-	value Object
+type setIteratorImpl[T any, V any] struct {
+	state *iteratorState[T, V]
+	value T
 }
 
-func keysSet(m *mapImpl) Set {
-	return &setImpl{hash: m.hash, equals: m.equals, root: m.root}
+func keysSet[K any, V any](m *mapImpl[K, V]) Set[K] {
+	return &setImpl[K, V]{hash: m.hash, equals: m.equals, layout: m.layout, root: m.root, size: m.size}
 }
 
-func (this *setImpl) withRoot(newRoot *node, delta int) *setImpl {
+func (this *setImpl[T, V]) withRoot(newRoot *node[T, V], delta int) *setImpl[T, V] {
 	newSet := *this
 	newSet.root = newRoot
 	newSet.size += delta
 	return &newSet
 }
 
-func CreateSet(hash HashFunc, equals EqualsFunc) Set {
-	return &setImpl{hash: hash, equals: equals, root: emptyNode()}
+// CreateSet creates an empty Set of Object values.  It is retained for
+// compatibility with code written before Set accepted a type parameter.
+func CreateSet(hash HashFunc[Object], equals EqualsFunc[Object]) Set[Object] {
+	return NewSet[Object](hash, equals)
 }
 
-func (this *setImpl) Add(key Object) Set {
-	newRoot, delta := this.root.assign(this.hash(key), key, nil, this.equals)
+// NewSet creates an empty Set whose values are hashed and compared using the
+// provided functions.
+func NewSet[T any](hash HashFunc[T], equals EqualsFunc[T]) Set[T] {
+	return &setImpl[T, struct{}]{hash: hash, equals: equals, layout: newLayoutToken(), root: emptyNode[T, struct{}]()}
+}
+
+// NewComparableSet creates an empty Set for values that can be compared using ==.
+// Values are hashed using hash/maphash with a seed chosen randomly when the program
+// starts.
+func NewComparableSet[T comparable]() Set[T] {
+	return NewSet[T](comparableHash[T](), comparableEquals[T])
+}
+
+// SetHashFunc returns a HashFunc that computes the HashCode of a Set.  Together
+// with SetEqualsFunc it allows sets to be used as keys of maps or values of sets.
+func SetHashFunc[T any]() HashFunc[Set[T]] {
+	return func(s Set[T]) HashCode {
+		return s.HashCode()
+	}
+}
+
+// SetEqualsFunc returns an EqualsFunc that compares sets using Set.Equals.
+func SetEqualsFunc[T any]() EqualsFunc[Set[T]] {
+	return func(a Set[T], b Set[T]) bool {
+		return a.Equals(b)
+	}
+}
+
+// FoldSet combines every value of s into a single value by calling f with the
+// result of the previous call, starting with initial.
+func FoldSet[T any, A any](s Set[T], initial A, f func(acc A, value T) A) A {
+	answer := initial
+	s.ForEach(func(value T) {
+		answer = f(answer, value)
+	})
+	return answer
+}
+
+// MapTo returns a set containing the result of calling f for every value in s.
+// The new set hashes and compares its values using hash and equals.
+func MapTo[T any, U any](s Set[T], f func(T) U, hash HashFunc[U], equals EqualsFunc[U]) Set[U] {
+	builder := NewSetBuilder[U](hash, equals)
+	s.ForEach(func(value T) {
+		builder.Add(f(value))
+	})
+	return builder.Build()
+}
+
+// GroupBy returns a map from the result of calling keyFn for each value in s to the
+// set of values producing that key.  The map hashes and compares its keys using
+// hash and equals while the sets use the same functions as s.
+func GroupBy[T any, G any](s Set[T], keyFn func(T) G, hash HashFunc[G], equals EqualsFunc[G]) Map[G, Set[T]] {
+	groups := NewMapBuilder[G, SetBuilder[T]](hash, equals)
+	s.ForEach(func(value T) {
+		key := keyFn(value)
+		group, ok := groups.Lookup(key)
+		if !ok {
+			group = s.emptyBuilder()
+			groups.Assign(key, group)
+		}
+		group.Add(value)
+	})
+	answer := NewMapBuilder[G, Set[T]](hash, equals)
+	groups.Build().ForEach(func(key G, group SetBuilder[T]) {
+		answer.Assign(key, group.Build())
+	})
+	return answer.Build()
+}
+
+func (this *setImpl[T, V]) Add(key T) Set[T] {
+	var zero V
+	newRoot, delta := this.root.assign(this.hash(key), 0, key, zero, 0, this.equals)
 	return this.withRoot(newRoot, delta)
 }
 
-func (this *setImpl) Contains(key Object) bool {
-	return this.root.contains(this.hash(key), key, this.equals)
+func (this *setImpl[T, V]) Contains(key T) bool {
+	return this.root.contains(this.hash(key), 0, key, this.equals)
 }
 
-func (this *setImpl) Delete(key Object) Set {
-	newRoot, delta := this.root.delete(this.hash(key), key, this.equals)
+func (this *setImpl[T, V]) Delete(key T) Set[T] {
+	newRoot, delta := this.root.delete(this.hash(key), 0, key, this.equals)
 	if newRoot == this.root {
 		return this
 	} else {
 		if newRoot == nil {
-			newRoot = emptyNode()
+			newRoot = emptyNode[T, V]()
 		}
 		return this.withRoot(newRoot, delta)
 	}
 }
 
-func (this *setImpl) Size() int {
+// AddAll adds every value in values.  The changes are grouped by hash so that each
+// node of the trie is copied at most once.  The receiver is returned if nothing
+// changed.
+func (this *setImpl[T, V]) AddAll(values []T) Set[T] {
+	batch := make([]batchEntry[T, V], len(values))
+	for i, value := range values {
+		batch[i] = batchEntry[T, V]{hash: this.hash(value), key: value}
+	}
+	return this.withCombinedRoot(this.root.applyBatch(batch, 0, this.equals))
+}
+
+// DeleteAll removes every value in values.  The changes are grouped by hash so that
+// each node of the trie is copied at most once.  The receiver is returned if
+// nothing changed.
+func (this *setImpl[T, V]) DeleteAll(values []T) Set[T] {
+	batch := make([]batchEntry[T, V], len(values))
+	for i, value := range values {
+		batch[i] = batchEntry[T, V]{hash: this.hash(value), key: value, delete: true}
+	}
+	return this.withCombinedRoot(this.root.applyBatch(batch, 0, this.equals))
+}
+
+func (this *setImpl[T, V]) Size() int {
 	return this.size
 }
 
-func (this *setImpl) ForEach(v SetVisitor) {
-	this.root.forEach(func(value Object, _ Object) {
+func (this *setImpl[T, V]) ForEach(v SetVisitor[T]) {
+	this.root.forEach(func(value T, _ V) {
 		v(value)
 	})
 }
 
-func (this *setImpl) Union(s Set) Set {
-	var larger, smaller Set
+// ForEachWhile calls v for each value in the set until v returns false.  It
+// returns true if every value was visited.
+func (this *setImpl[T, V]) ForEachWhile(v SetPredicate[T]) bool {
+	return this.root.forEachWhile(func(value T, _ V) bool {
+		return v(value)
+	})
+}
+
+// Find returns the first value for which p returns true.  The second result is
+// false if no value matches.
+func (this *setImpl[T, V]) Find(p SetPredicate[T]) (T, bool) {
+	var found T
+	ok := !this.ForEachWhile(func(value T) bool {
+		if p(value) {
+			found = value
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// Any reports whether p returns true for at least one value in the set.
+func (this *setImpl[T, V]) Any(p SetPredicate[T]) bool {
+	_, found := this.Find(p)
+	return found
+}
+
+// Every reports whether p returns true for every value in the set.  It returns
+// true for an empty set.
+func (this *setImpl[T, V]) Every(p SetPredicate[T]) bool {
+	return this.ForEachWhile(p)
+}
+
+// Filter returns a set containing only the values for which p returns true.
+// Subtrees of the trie in which every value matches are shared with the result.
+func (this *setImpl[T, V]) Filter(p SetPredicate[T]) Set[T] {
+	return this.withCombinedRoot(this.root.filter(func(value T, _ V) bool {
+		return p(value)
+	}))
+}
+
+// Partition returns a set containing the values for which p returns true and a set
+// containing the rest.  p is called once for each value.
+func (this *setImpl[T, V]) Partition(p SetPredicate[T]) (Set[T], Set[T]) {
+	matching, other, otherCount := this.root.partition(func(value T, _ V) bool {
+		return p(value)
+	})
+	return this.withCombinedRoot(matching, -otherCount), this.withCombinedRoot(other, otherCount-this.size)
+}
+
+// Union returns a set containing the values found in either set.  When both sets
+// were derived from the same original set the tries are combined node by node and
+// subtrees found in only one of them are shared with the result.
+func (this *setImpl[T, V]) Union(s Set[T]) Set[T] {
+	if other, ok := this.sameLayout(s); ok {
+		merger := &nodeMerger[T, V]{equals: this.equals, sharedNodes: keepSharedNodes}
+		return this.withCombinedRoot(merger.merge(this.root, other.root))
+	}
+
+	var larger, smaller Set[T]
 	if this.Size() > s.Size() {
 		larger, smaller = this, s
 	} else {
 		larger, smaller = s, this
 	}
-	smaller.ForEach(func(v Object) {
+	smaller.ForEach(func(v T) {
 		larger = larger.Add(v)
 	})
 	return larger
 }
 
-func (this *setImpl) Intersection(s Set) Set {
-	var larger, smaller Set
+// Intersection returns a set containing the values found in both sets.
+func (this *setImpl[T, V]) Intersection(s Set[T]) Set[T] {
+	if other, ok := this.sameLayout(s); ok {
+		merger := &nodeMerger[T, V]{equals: this.equals, sharedNodes: keepSharedNodes}
+		return this.withCombinedRoot(merger.intersect(this.root, other.root))
+	}
+
+	var larger, smaller Set[T]
 	if this.Size() > s.Size() {
 		larger, smaller = this, s
 	} else {
 		larger, smaller = s, this
 	}
-	smaller.ForEach(func(v Object) {
+	smaller.ForEach(func(v T) {
 		if !larger.Contains(v) {
 			smaller = smaller.Delete(v)
 		}
@@ -107,7 +292,119 @@ func (this *setImpl) Intersection(s Set) Set {
 	return smaller
 }
 
-func (this *setImpl) checkInvariants(report reporter) {
+// Difference returns a set containing the values of this set that are not in s.
+func (this *setImpl[T, V]) Difference(s Set[T]) Set[T] {
+	if other, ok := this.sameLayout(s); ok {
+		return this.withCombinedRoot(this.root.difference(other.root, this.equals))
+	}
+
+	var answer Set[T] = this
+	if this.Size() <= s.Size() {
+		this.ForEach(func(v T) {
+			if s.Contains(v) {
+				answer = answer.Delete(v)
+			}
+		})
+	} else {
+		s.ForEach(func(v T) {
+			answer = answer.Delete(v)
+		})
+	}
+	return answer
+}
+
+// SymmetricDifference returns a set containing the values found in exactly one of
+// the two sets.
+func (this *setImpl[T, V]) SymmetricDifference(s Set[T]) Set[T] {
+	if other, ok := this.sameLayout(s); ok {
+		merger := &nodeMerger[T, V]{equals: this.equals, resolve: dropLeftValue[T, V], sharedNodes: dropSharedNodes}
+		return this.withCombinedRoot(merger.merge(this.root, other.root))
+	}
+
+	var answer Set[T] = this
+	s.ForEach(func(v T) {
+		if this.Contains(v) {
+			answer = answer.Delete(v)
+		} else {
+			answer = answer.Add(v)
+		}
+	})
+	return answer
+}
+
+// IsSubsetOf reports whether every value in this set is also in s.
+func (this *setImpl[T, V]) IsSubsetOf(s Set[T]) bool {
+	if this.Size() > s.Size() {
+		return false
+	}
+	if other, ok := this.sameLayout(s); ok {
+		return this.root.isSubsetOf(other.root, this.equals)
+	}
+	for i := this.Iterate(); i.Next(); {
+		if !s.Contains(i.Get()) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSupersetOf reports whether every value in s is also in this set.
+func (this *setImpl[T, V]) IsSupersetOf(s Set[T]) bool {
+	return s.IsSubsetOf(this)
+}
+
+// IsDisjoint reports whether the two sets have no values in common.
+func (this *setImpl[T, V]) IsDisjoint(s Set[T]) bool {
+	var larger, smaller Set[T]
+	if this.Size() > s.Size() {
+		larger, smaller = this, s
+	} else {
+		larger, smaller = s, this
+	}
+	for i := smaller.Iterate(); i.Next(); {
+		if larger.Contains(i.Get()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equals reports whether the two sets contain the same values.
+func (this *setImpl[T, V]) Equals(s Set[T]) bool {
+	if other, ok := this.sameLayout(s); ok && this.root.contentHash != other.root.contentHash {
+		return false
+	}
+	return this.Size() == s.Size() && this.IsSubsetOf(s)
+}
+
+// HashCode returns a hash code computed from every value in the set.  The result
+// does not depend on iteration order so sets that are equal according to Equals
+// have the same hash code.  It is maintained by the trie so it takes constant time.
+func (this *setImpl[T, V]) HashCode() HashCode {
+	return this.root.contentHash
+}
+
+// sameLayout returns s as a *setImpl if its trie shares the layout of this set's
+// trie so that the two can be combined node by node.
+func (this *setImpl[T, V]) sameLayout(s Set[T]) (*setImpl[T, V], bool) {
+	other, ok := s.(*setImpl[T, V])
+	return other, ok && other.layout == this.layout
+}
+
+func (this *setImpl[T, V]) withCombinedRoot(newRoot *node[T, V], delta int) Set[T] {
+	if newRoot == this.root {
+		return this
+	} else if newRoot == nil {
+		newRoot = emptyNode[T, V]()
+	}
+	return this.withRoot(newRoot, delta)
+}
+
+func dropLeftValue[T any, V any](_ T, left V, _ V) (V, bool) {
+	return left, false
+}
+
+func (this *setImpl[T, V]) checkInvariants(report reporter) {
 	this.root.checkInvariants(this.hash, this.equals, 0, report)
 	size := 0
 	for i := this.Iterate(); i.Next(); {
@@ -120,8 +417,21 @@ func (this *setImpl) checkInvariants(report reporter) {
 	if this.size != size {
 		report(fmt.Sprintf("Size() does not match number of keys in iterator: expected=%d actual=%d", this.size, size))
 	}
+	var hashCode HashCode
+	this.ForEach(func(v T) {
+		hashCode += elementHash(this.hash(v))
+	})
+	if hashCode != this.HashCode() {
+		report(fmt.Sprintf("HashCode() does not match hash of values: expected=%d actual=%d", hashCode, this.HashCode()))
+	}
+	if !this.Equals(this) || !this.IsSubsetOf(this) || !this.IsSupersetOf(this) {
+		report("set is not equal to itself")
+	}
+	if this.IsDisjoint(this) != (this.size == 0) {
+		report(fmt.Sprintf("IsDisjoint with itself returned incorrect result for size %d", this.size))
+	}
 	i2 := this.Iterate()
-	this.ForEach(func(key Object) {
+	this.ForEach(func(key T) {
 		if !i2.Next() {
 			report(fmt.Sprintf("Next() returned false in ForEach"))
 		}
@@ -132,11 +442,31 @@ func (this *setImpl) checkInvariants(report reporter) {
 	})
 }
 
-func (this *setImpl) Iterate() SetIterator {
-	return &setIteratorImpl{state: this.root.createIteratorState(nil)}
+func (this *setImpl[T, V]) Iterate() SetIterator[T] {
+	return &setIteratorImpl[T, V]{state: this.root.createIteratorState(nil)}
+}
+
+// All returns an iterator over the values of the set for use with range.
+func (this *setImpl[T, V]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice returns a new slice containing the values of the set in iteration order.
+func (this *setImpl[T, V]) ToSlice() []T {
+	answer := make([]T, 0, this.size)
+	this.ForEach(func(value T) {
+		answer = append(answer, value)
+	})
+	return answer
 }
 
-func (this *setIteratorImpl) Next() bool {
+func (this *setIteratorImpl[T, V]) Next() bool {
 	if this.state == nil {
 		return false
 	} else {
@@ -145,6 +475,6 @@ func (this *setIteratorImpl) Next() bool {
 	}
 }
 
-func (this *setIteratorImpl) Get() Object {
+func (this *setIteratorImpl[T, V]) Get() T {
 	return this.value
 }
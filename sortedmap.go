@@ -0,0 +1,163 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// SortedMap is an immutable map that keeps its keys in the order defined by a
+// CompareFunc.  Iteration visits keys in ascending order.
+type SortedMap[K any, V any] interface {
+	Assign(key K, value V) SortedMap[K, V]
+	Get(key K) V
+	Lookup(key K) (V, bool)
+	ContainsKey(key K) bool
+	Delete(key K) SortedMap[K, V]
+	Size() int
+	Iterate() MapIterator[K, V]
+	ForEach(v MapVisitor[K, V])
+	All() iter.Seq2[K, V]
+	First() (K, V, bool)
+	Last() (K, V, bool)
+	Floor(key K) (K, V, bool)
+	Ceiling(key K) (K, V, bool)
+	Range(from K, to K) MapIterator[K, V]
+	checkInvariants(report reporter)
+}
+
+type sortedMapImpl[K any, V any] struct {
+	compare CompareFunc[K]
+	root    *treeNode[K, V]
+	size    int
+}
+
+// NewSortedMap creates an empty SortedMap that orders its keys using compare.
+func NewSortedMap[K any, V any](compare CompareFunc[K]) SortedMap[K, V] {
+	return &sortedMapImpl[K, V]{compare: compare}
+}
+
+func (this *sortedMapImpl[K, V]) withRoot(newRoot *treeNode[K, V], delta int) *sortedMapImpl[K, V] {
+	newMap := *this
+	newMap.root = newRoot.blacken()
+	newMap.size += delta
+	return &newMap
+}
+
+func (this *sortedMapImpl[K, V]) Assign(key K, value V) SortedMap[K, V] {
+	newRoot, delta := this.root.assign(key, value, this.compare)
+	if newRoot == this.root {
+		return this
+	}
+	return this.withRoot(newRoot, delta)
+}
+
+func (this *sortedMapImpl[K, V]) Get(key K) V {
+	value, _ := this.Lookup(key)
+	return value
+}
+
+func (this *sortedMapImpl[K, V]) Lookup(key K) (V, bool) {
+	if n := this.root.find(key, this.compare); n != nil {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (this *sortedMapImpl[K, V]) ContainsKey(key K) bool {
+	return this.root.find(key, this.compare) != nil
+}
+
+func (this *sortedMapImpl[K, V]) Delete(key K) SortedMap[K, V] {
+	if !this.ContainsKey(key) {
+		return this
+	}
+	return this.withRoot(this.root.remove(key, this.compare), -1)
+}
+
+func (this *sortedMapImpl[K, V]) Size() int {
+	return this.size
+}
+
+func (this *sortedMapImpl[K, V]) Iterate() MapIterator[K, V] {
+	var zero K
+	return this.root.createIterator(this.compare, zero, false, false)
+}
+
+func (this *sortedMapImpl[K, V]) ForEach(v MapVisitor[K, V]) {
+	this.root.forEach(v)
+}
+
+// All returns an iterator over the keys and values of the map in ascending key
+// order for use with range.
+func (this *sortedMapImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+// First returns the smallest key and its value.  The final result is false if the
+// map is empty.
+func (this *sortedMapImpl[K, V]) First() (K, V, bool) {
+	return nodeResult(this.root.min())
+}
+
+// Last returns the largest key and its value.  The final result is false if the
+// map is empty.
+func (this *sortedMapImpl[K, V]) Last() (K, V, bool) {
+	return nodeResult(this.root.max())
+}
+
+// Floor returns the greatest key less than or equal to key and its value.  The
+// final result is false if there is no such key.
+func (this *sortedMapImpl[K, V]) Floor(key K) (K, V, bool) {
+	return nodeResult(this.root.floor(key, this.compare))
+}
+
+// Ceiling returns the least key greater than or equal to key and its value.  The
+// final result is false if there is no such key.
+func (this *sortedMapImpl[K, V]) Ceiling(key K) (K, V, bool) {
+	return nodeResult(this.root.ceiling(key, this.compare))
+}
+
+// Range returns an iterator over the keys that are greater than or equal to from
+// and less than to in ascending order.
+func (this *sortedMapImpl[K, V]) Range(from K, to K) MapIterator[K, V] {
+	return this.root.createIterator(this.compare, from, true, false).withLimit(to)
+}
+
+func nodeResult[K any, V any](n *treeNode[K, V]) (K, V, bool) {
+	if n == nil {
+		var key K
+		var value V
+		return key, value, false
+	}
+	return n.key, n.value, true
+}
+
+func (this *sortedMapImpl[K, V]) checkInvariants(report reporter) {
+	if isRed(this.root) {
+		report("red root detected")
+	}
+	this.root.checkInvariants(this.compare, report)
+	size := 0
+	var previous K
+	for i := this.Iterate(); i.Next(); {
+		key, expected := i.Get()
+		if size > 0 && this.compare(previous, key) >= 0 {
+			report(fmt.Sprintf("keys out of order: previous=%v key=%v", previous, key))
+		}
+		if actual, ok := this.Lookup(key); !ok || !sameValue(expected, actual) {
+			report(fmt.Sprintf("Get returned incorrect result: key=%v expected=%v actual=%v", key, expected, actual))
+		}
+		previous = key
+		size++
+	}
+	if this.size != size {
+		report(fmt.Sprintf("Size() does not match number of keys in iterator: expected=%d actual=%d", this.size, size))
+	}
+}
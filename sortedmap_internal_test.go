@@ -0,0 +1,126 @@
+package immutableMap
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSortedMap(t *testing.T) {
+	m := NewSortedMap[int, int](cmp.Compare[int])
+	expected := make(map[int]int)
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 5000; i++ {
+		key := random.Intn(1000)
+		if random.Intn(3) == 0 {
+			m = m.Delete(key)
+			delete(expected, key)
+		} else {
+			m = m.Assign(key, i)
+			expected[key] = i
+		}
+		if i%500 == 0 {
+			m.checkInvariants(createReporter(t))
+		}
+	}
+	m.checkInvariants(createReporter(t))
+
+	if m.Size() != len(expected) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(expected), m.Size()))
+	}
+	keys := make([]int, 0, len(expected))
+	for key, value := range expected {
+		keys = append(keys, key)
+		if v, ok := m.Lookup(key); !ok || v != value {
+			t.Error(fmt.Sprintf("Lookup mismatch: key=%d expected=%d actual=%d ok=%v", key, value, v, ok))
+		}
+	}
+	sort.Ints(keys)
+	index := 0
+	for key, value := range m.All() {
+		if key != keys[index] || value != expected[key] {
+			t.Error(fmt.Sprintf("iteration mismatch at %d: key=%d value=%d", index, key, value))
+		}
+		index++
+	}
+
+	for _, key := range keys {
+		m = m.Delete(key)
+	}
+	m.checkInvariants(createReporter(t))
+	if m.Size() != 0 {
+		t.Error(fmt.Sprintf("expected empty map but got size %d", m.Size()))
+	}
+}
+
+func TestSortedMapPersistence(t *testing.T) {
+	m := NewSortedMap[string, int](cmp.Compare[string])
+	for i := 0; i < 100; i++ {
+		m = m.Assign(val(i), i)
+	}
+	before := m
+	after := m.Delete(val(50)).Assign(val(5), -5).Assign("extra", 1)
+	before.checkInvariants(createReporter(t))
+	after.checkInvariants(createReporter(t))
+	if before.Size() != 100 || before.Get(val(5)) != 5 || !before.ContainsKey(val(50)) || before.ContainsKey("extra") {
+		t.Error("changes to a derived map modified the original")
+	}
+	if after.Size() != 100 || after.Get(val(5)) != -5 || after.ContainsKey(val(50)) {
+		t.Error(fmt.Sprintf("derived map mismatch: size=%d", after.Size()))
+	}
+	if m.Assign(val(5), 5) != m || m.Delete("missing") != m {
+		t.Error("unchanged map was copied")
+	}
+}
+
+func TestSortedMapNavigation(t *testing.T) {
+	m := NewSortedMap[int, string](cmp.Compare[int])
+	if _, _, ok := m.First(); ok {
+		t.Error("First returned true for empty map")
+	}
+	for i := 10; i <= 100; i += 10 {
+		m = m.Assign(i, val(i))
+	}
+
+	check := func(name string, key int, value string, ok bool, expected int) {
+		if expected < 0 {
+			if ok {
+				t.Error(fmt.Sprintf("%s returned %d for missing key", name, key))
+			}
+		} else if !ok || key != expected || value != val(expected) {
+			t.Error(fmt.Sprintf("%s mismatch: expected=%d actual=%d,%s,%v", name, expected, key, value, ok))
+		}
+	}
+	key, value, ok := m.First()
+	check("First", key, value, ok, 10)
+	key, value, ok = m.Last()
+	check("Last", key, value, ok, 100)
+	key, value, ok = m.Floor(55)
+	check("Floor", key, value, ok, 50)
+	key, value, ok = m.Floor(60)
+	check("Floor", key, value, ok, 60)
+	key, value, ok = m.Floor(5)
+	check("Floor", key, value, ok, -1)
+	key, value, ok = m.Ceiling(55)
+	check("Ceiling", key, value, ok, 60)
+	key, value, ok = m.Ceiling(100)
+	check("Ceiling", key, value, ok, 100)
+	key, value, ok = m.Ceiling(101)
+	check("Ceiling", key, value, ok, -1)
+
+	rangeString := func(from int, to int) string {
+		answer := "|"
+		for i := m.Range(from, to); i.Next(); {
+			key, _ := i.Get()
+			answer += fmt.Sprintf("%d|", key)
+		}
+		return answer
+	}
+	assertString(rangeString(25, 70), "|30|40|50|60|", t)
+	assertString(rangeString(30, 31), "|30|", t)
+	assertString(rangeString(0, 1000), "|10|20|30|40|50|60|70|80|90|100|", t)
+	assertString(rangeString(31, 39), "|", t)
+	assertString(rangeString(70, 30), "|", t)
+}
@@ -0,0 +1,268 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// SortedSet is an immutable set that keeps its values in the order defined by a
+// CompareFunc.  Iteration visits values in ascending order unless otherwise noted.
+// HeadSet, TailSet, and SubSet return views limited to a range of values that
+// share their tree with the set they came from.
+type SortedSet[T any] interface {
+	Add(value T) SortedSet[T]
+	Delete(value T) SortedSet[T]
+	Contains(value T) bool
+	Size() int
+	Iterate() SetIterator[T]
+	IterateDescending() SetIterator[T]
+	ForEach(v SetVisitor[T])
+	All() iter.Seq[T]
+	Backward() iter.Seq[T]
+	First() (T, bool)
+	Last() (T, bool)
+	HeadSet(to T) SortedSet[T]
+	TailSet(from T) SortedSet[T]
+	SubSet(from T, to T) SortedSet[T]
+	Rank(value T) int
+	Select(index int) (T, bool)
+	checkInvariants(report reporter)
+}
+
+// sortedSetImpl stores its values as the keys of a tree.  Views limit the set to
+// values that are not before from (when hasFrom is true) and are before to (when
+// hasTo is true).  Subtree sizes in the tree allow the size of a view to be
+// computed without visiting its values.
+type sortedSetImpl[T any] struct {
+	compare CompareFunc[T]
+	root    *treeNode[T, struct{}]
+	from    T
+	hasFrom bool
+	to      T
+	hasTo   bool
+}
+
+type sortedSetIteratorImpl[T any] struct {
+	iterator *treeIterator[T, struct{}]
+}
+
+// NewSortedSet creates an empty SortedSet that orders its values using compare.
+func NewSortedSet[T any](compare CompareFunc[T]) SortedSet[T] {
+	return &sortedSetImpl[T]{compare: compare}
+}
+
+func (this *sortedSetImpl[T]) withRoot(newRoot *treeNode[T, struct{}]) *sortedSetImpl[T] {
+	newSet := *this
+	newSet.root = newRoot.blacken()
+	return &newSet
+}
+
+func (this *sortedSetImpl[T]) inRange(value T) bool {
+	return (!this.hasFrom || this.compare(value, this.from) >= 0) && (!this.hasTo || this.compare(value, this.to) < 0)
+}
+
+// lowRank returns the number of values in the tree that are before the view.
+func (this *sortedSetImpl[T]) lowRank() int {
+	if this.hasFrom {
+		return this.root.rank(this.from, this.compare)
+	}
+	return 0
+}
+
+// highRank returns the number of values in the tree that are before the end of
+// the view.
+func (this *sortedSetImpl[T]) highRank() int {
+	if this.hasTo {
+		return this.root.rank(this.to, this.compare)
+	}
+	return treeSize(this.root)
+}
+
+// Add returns a set containing the value.  Views panic if the value is outside of
+// their range.
+func (this *sortedSetImpl[T]) Add(value T) SortedSet[T] {
+	if !this.inRange(value) {
+		panic(fmt.Sprintf("value outside of sorted set view: %v", value))
+	}
+	newRoot, _ := this.root.assign(value, struct{}{}, this.compare)
+	if newRoot == this.root {
+		return this
+	}
+	return this.withRoot(newRoot)
+}
+
+func (this *sortedSetImpl[T]) Delete(value T) SortedSet[T] {
+	if !this.Contains(value) {
+		return this
+	}
+	return this.withRoot(this.root.remove(value, this.compare))
+}
+
+func (this *sortedSetImpl[T]) Contains(value T) bool {
+	return this.inRange(value) && this.root.find(value, this.compare) != nil
+}
+
+func (this *sortedSetImpl[T]) Size() int {
+	return max(0, this.highRank()-this.lowRank())
+}
+
+func (this *sortedSetImpl[T]) Iterate() SetIterator[T] {
+	iterator := this.root.createIterator(this.compare, this.from, this.hasFrom, false)
+	if this.hasTo {
+		iterator.withLimit(this.to)
+	}
+	return &sortedSetIteratorImpl[T]{iterator: iterator}
+}
+
+// IterateDescending returns an iterator that visits the values in descending order.
+func (this *sortedSetImpl[T]) IterateDescending() SetIterator[T] {
+	iterator := this.root.createIterator(this.compare, this.to, this.hasTo, true)
+	if this.hasFrom {
+		iterator.withLimit(this.from)
+	}
+	return &sortedSetIteratorImpl[T]{iterator: iterator}
+}
+
+func (this *sortedSetImpl[T]) ForEach(v SetVisitor[T]) {
+	for i := this.Iterate(); i.Next(); {
+		v(i.Get())
+	}
+}
+
+// All returns an iterator over the values of the set in ascending order for use
+// with range.
+func (this *sortedSetImpl[T]) All() iter.Seq[T] {
+	return iterateSeq(this.Iterate)
+}
+
+// Backward returns an iterator over the values of the set in descending order for
+// use with range.
+func (this *sortedSetImpl[T]) Backward() iter.Seq[T] {
+	return iterateSeq(this.IterateDescending)
+}
+
+// iterateSeq returns a sequence that calls create for a new iterator each time it
+// is ranged over so the sequence can be used more than once.
+func iterateSeq[T any](create func() SetIterator[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := create(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+// First returns the smallest value.  The second result is false if the set is
+// empty.
+func (this *sortedSetImpl[T]) First() (T, bool) {
+	return firstValue(this.Iterate())
+}
+
+// Last returns the largest value.  The second result is false if the set is empty.
+func (this *sortedSetImpl[T]) Last() (T, bool) {
+	return firstValue(this.IterateDescending())
+}
+
+func firstValue[T any](i SetIterator[T]) (T, bool) {
+	if i.Next() {
+		return i.Get(), true
+	}
+	var zero T
+	return zero, false
+}
+
+// HeadSet returns a view of the values that are before to.
+func (this *sortedSetImpl[T]) HeadSet(to T) SortedSet[T] {
+	return this.withBounds(this.from, false, to, true)
+}
+
+// TailSet returns a view of the values that are not before from.
+func (this *sortedSetImpl[T]) TailSet(from T) SortedSet[T] {
+	return this.withBounds(from, true, this.to, false)
+}
+
+// SubSet returns a view of the values that are not before from and are before to.
+func (this *sortedSetImpl[T]) SubSet(from T, to T) SortedSet[T] {
+	return this.withBounds(from, true, to, true)
+}
+
+// withBounds returns a view limited to the intersection of this set's range and the
+// provided bounds.
+func (this *sortedSetImpl[T]) withBounds(from T, hasFrom bool, to T, hasTo bool) *sortedSetImpl[T] {
+	newSet := *this
+	if hasFrom && (!this.hasFrom || this.compare(from, this.from) > 0) {
+		newSet.from = from
+		newSet.hasFrom = true
+	}
+	if hasTo && (!this.hasTo || this.compare(to, this.to) < 0) {
+		newSet.to = to
+		newSet.hasTo = true
+	}
+	return &newSet
+}
+
+// Rank returns the number of values in the set that are before value.
+func (this *sortedSetImpl[T]) Rank(value T) int {
+	rank := this.root.rank(value, this.compare)
+	if this.hasTo {
+		rank = min(rank, this.highRank())
+	}
+	return max(0, rank-this.lowRank())
+}
+
+// Select returns the value whose Rank is index.  The second result is false if the
+// index is out of range.
+func (this *sortedSetImpl[T]) Select(index int) (T, bool) {
+	if index < 0 || index >= this.Size() {
+		var zero T
+		return zero, false
+	}
+	return this.root.selectIndex(this.lowRank() + index).key, true
+}
+
+func (this *sortedSetIteratorImpl[T]) Next() bool {
+	return this.iterator.Next()
+}
+
+func (this *sortedSetIteratorImpl[T]) Get() T {
+	value, _ := this.iterator.Get()
+	return value
+}
+
+func (this *sortedSetImpl[T]) checkInvariants(report reporter) {
+	if isRed(this.root) {
+		report("red root detected")
+	}
+	this.root.checkInvariants(this.compare, report)
+	var values []T
+	for value := range this.All() {
+		if len(values) > 0 && this.compare(values[len(values)-1], value) >= 0 {
+			report(fmt.Sprintf("values out of order: previous=%v value=%v", values[len(values)-1], value))
+		}
+		if !this.Contains(value) {
+			report(fmt.Sprintf("Contains returned false for iterated value: value=%v", value))
+		}
+		if rank := this.Rank(value); rank != len(values) {
+			report(fmt.Sprintf("Rank returned incorrect result: value=%v expected=%d actual=%d", value, len(values), rank))
+		}
+		if selected, ok := this.Select(len(values)); !ok || this.compare(selected, value) != 0 {
+			report(fmt.Sprintf("Select returned incorrect result: index=%d expected=%v actual=%v", len(values), value, selected))
+		}
+		values = append(values, value)
+	}
+	if this.Size() != len(values) {
+		report(fmt.Sprintf("Size() does not match number of values in iterator: expected=%d actual=%d", this.Size(), len(values)))
+	}
+	index := len(values)
+	for value := range this.Backward() {
+		index--
+		if index < 0 || this.compare(values[index], value) != 0 {
+			report(fmt.Sprintf("descending iteration mismatch: index=%d value=%v", index, value))
+			return
+		}
+	}
+	if index != 0 {
+		report(fmt.Sprintf("descending iteration missed values: remaining=%d", index))
+	}
+}
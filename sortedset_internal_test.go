@@ -0,0 +1,141 @@
+package immutableMap
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func orderedSetString(s SortedSet[int]) string {
+	answer := "|"
+	for value := range s.All() {
+		answer += fmt.Sprintf("%d|", value)
+	}
+	return answer
+}
+
+func TestSortedSet(t *testing.T) {
+	s := NewSortedSet[int](cmp.Compare[int])
+	expected := make(map[int]bool)
+	random := rand.New(rand.NewSource(19))
+	for i := 0; i < 5000; i++ {
+		value := random.Intn(1000)
+		if random.Intn(3) == 0 {
+			s = s.Delete(value)
+			delete(expected, value)
+		} else {
+			s = s.Add(value)
+			expected[value] = true
+		}
+		if i%500 == 0 {
+			s.checkInvariants(createReporter(t))
+		}
+	}
+	s.checkInvariants(createReporter(t))
+
+	if s.Size() != len(expected) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(expected), s.Size()))
+	}
+	values := make([]int, 0, len(expected))
+	for value := range expected {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+	for index, value := range values {
+		if !s.Contains(value) || s.Rank(value) != index {
+			t.Error(fmt.Sprintf("lookup mismatch: value=%d index=%d rank=%d", value, index, s.Rank(value)))
+		}
+		if selected, ok := s.Select(index); !ok || selected != value {
+			t.Error(fmt.Sprintf("Select mismatch: index=%d expected=%d actual=%d", index, value, selected))
+		}
+	}
+	if _, ok := s.Select(len(values)); ok {
+		t.Error("Select returned true for index past the end")
+	}
+
+	for _, value := range values {
+		s = s.Delete(value)
+	}
+	s.checkInvariants(createReporter(t))
+	if s.Size() != 0 {
+		t.Error(fmt.Sprintf("expected empty set but got size %d", s.Size()))
+	}
+}
+
+func TestSortedSetPersistence(t *testing.T) {
+	s := NewSortedSet[int](cmp.Compare[int])
+	for i := 0; i < 100; i++ {
+		s = s.Add(i)
+	}
+	before := s
+	after := s.Delete(50).Add(500)
+	before.checkInvariants(createReporter(t))
+	after.checkInvariants(createReporter(t))
+	if before.Size() != 100 || !before.Contains(50) || before.Contains(500) {
+		t.Error("changes to a derived set modified the original")
+	}
+	if after.Size() != 100 || after.Contains(50) || after.Rank(500) != 99 {
+		t.Error(fmt.Sprintf("derived set mismatch: size=%d", after.Size()))
+	}
+	if s.Add(5) != s || s.Delete(-1) != s {
+		t.Error("unchanged set was copied")
+	}
+}
+
+func TestSortedSetViews(t *testing.T) {
+	s := NewSortedSet[int](cmp.Compare[int])
+	if _, ok := s.First(); ok {
+		t.Error("First returned true for empty set")
+	}
+	for i := 10; i <= 100; i += 10 {
+		s = s.Add(i)
+	}
+
+	assertString(orderedSetString(s.HeadSet(40)), "|10|20|30|", t)
+	assertString(orderedSetString(s.TailSet(75)), "|80|90|100|", t)
+	assertString(orderedSetString(s.SubSet(25, 70)), "|30|40|50|60|", t)
+	assertString(orderedSetString(s.SubSet(25, 70).HeadSet(90)), "|30|40|50|60|", t)
+	assertString(orderedSetString(s.SubSet(25, 70).TailSet(50)), "|50|60|", t)
+	assertString(orderedSetString(s.SubSet(70, 30)), "|", t)
+
+	backward := s.SubSet(25, 70).Backward()
+	for pass := 0; pass < 2; pass++ {
+		descending := "|"
+		for value := range backward {
+			descending += fmt.Sprintf("%d|", value)
+		}
+		assertString(descending, "|60|50|40|30|", t)
+	}
+
+	view := s.SubSet(25, 70)
+	view.checkInvariants(createReporter(t))
+	if view.Size() != 4 || view.Contains(20) || view.Contains(70) || !view.Contains(30) {
+		t.Error(fmt.Sprintf("view membership mismatch: size=%d", view.Size()))
+	}
+	if first, _ := view.First(); first != 30 {
+		t.Error(fmt.Sprintf("First mismatch: expected=30 actual=%d", first))
+	}
+	if last, _ := view.Last(); last != 60 {
+		t.Error(fmt.Sprintf("Last mismatch: expected=60 actual=%d", last))
+	}
+	if view.Rank(10) != 0 || view.Rank(45) != 2 || view.Rank(1000) != 4 {
+		t.Error(fmt.Sprintf("view Rank mismatch: %d %d %d", view.Rank(10), view.Rank(45), view.Rank(1000)))
+	}
+	if selected, ok := view.Select(1); !ok || selected != 40 {
+		t.Error(fmt.Sprintf("view Select mismatch: expected=40 actual=%d", selected))
+	}
+
+	view = view.Add(35).Delete(40).Delete(90)
+	view.checkInvariants(createReporter(t))
+	assertString(orderedSetString(view), "|30|35|50|60|", t)
+	assertString(orderedSetString(s), "|10|20|30|40|50|60|70|80|90|100|", t)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("adding a value outside of a view did not panic")
+		}
+	}()
+	view.Add(90)
+}
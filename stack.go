@@ -0,0 +1,188 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Stack is an immutable last in first out sequence.  Iteration visits values from
+// the top of the stack to the bottom.
+type Stack[T any] interface {
+	Push(value T) Stack[T]
+	Pop() (Stack[T], T, bool)
+	Peek() (T, bool)
+	Size() int
+	Iterate() Iterator[T]
+	ForEach(v SetVisitor[T])
+	All() iter.Seq[T]
+	ToSlice() []T
+	Equals(other Stack[T], equals EqualsFunc[T]) bool
+	checkInvariants(report reporter)
+}
+
+// listNode is a cell of a persistent singly linked list.  size is the number of
+// values in the list starting at this node.
+type listNode[T any] struct {
+	value T
+	next  *listNode[T]
+	size  int
+}
+
+type stackImpl[T any] struct {
+	top *listNode[T]
+}
+
+// listIteratorImpl visits the values of current followed by the values of reversed
+// in reverse order.
+type listIteratorImpl[T any] struct {
+	current  *listNode[T]
+	reversed *listNode[T]
+	value    T
+}
+
+// CreateStack creates an empty Stack.
+func CreateStack[T any]() Stack[T] {
+	return &stackImpl[T]{}
+}
+
+func listSize[T any](list *listNode[T]) int {
+	if list == nil {
+		return 0
+	}
+	return list.size
+}
+
+func (this *listNode[T]) push(value T) *listNode[T] {
+	return &listNode[T]{value: value, next: this, size: listSize(this) + 1}
+}
+
+// reverseOnto pushes the values of the list onto tail so the result holds them in
+// reverse order followed by the values of tail.
+func (this *listNode[T]) reverseOnto(tail *listNode[T]) *listNode[T] {
+	for n := this; n != nil; n = n.next {
+		tail = tail.push(n.value)
+	}
+	return tail
+}
+
+// split returns a copy of the first count nodes of the list and the remaining
+// nodes of the list.
+func (this *listNode[T]) split(count int) (*listNode[T], *listNode[T]) {
+	var reversed *listNode[T]
+	n := this
+	for ; count > 0; count-- {
+		reversed = reversed.push(n.value)
+		n = n.next
+	}
+	return reversed.reverseOnto(nil), n
+}
+
+func (this *listNode[T]) checkInvariants(report reporter) {
+	expected := 0
+	var sizes []int
+	for n := this; n != nil; n = n.next {
+		sizes = append(sizes, n.size)
+	}
+	for i := len(sizes) - 1; i >= 0; i-- {
+		expected++
+		if sizes[i] != expected {
+			report(fmt.Sprintf("list size mismatch: expected=%d actual=%d", expected, sizes[i]))
+			return
+		}
+	}
+}
+
+// listsEqual returns true if the iterators visit the same number of values and
+// equals returns true for each pair of values.
+func listsEqual[T any](a Iterator[T], b Iterator[T], equals EqualsFunc[T]) bool {
+	for a.Next() {
+		if !b.Next() || !equals(a.Get(), b.Get()) {
+			return false
+		}
+	}
+	return !b.Next()
+}
+
+func (this *stackImpl[T]) Push(value T) Stack[T] {
+	return &stackImpl[T]{top: this.top.push(value)}
+}
+
+// Pop returns a stack without its top value along with that value.  The final
+// result is false if the stack is empty.
+func (this *stackImpl[T]) Pop() (Stack[T], T, bool) {
+	if this.top == nil {
+		var zero T
+		return this, zero, false
+	}
+	return &stackImpl[T]{top: this.top.next}, this.top.value, true
+}
+
+// Peek returns the top value.  The second result is false if the stack is empty.
+func (this *stackImpl[T]) Peek() (T, bool) {
+	if this.top == nil {
+		var zero T
+		return zero, false
+	}
+	return this.top.value, true
+}
+
+func (this *stackImpl[T]) Size() int {
+	return listSize(this.top)
+}
+
+func (this *stackImpl[T]) Iterate() Iterator[T] {
+	return &listIteratorImpl[T]{current: this.top}
+}
+
+func (this *stackImpl[T]) ForEach(v SetVisitor[T]) {
+	for n := this.top; n != nil; n = n.next {
+		v(n.value)
+	}
+}
+
+// All returns an iterator over the values of the stack from top to bottom for use
+// with range.
+func (this *stackImpl[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *stackImpl[T]) ToSlice() []T {
+	answer := make([]T, 0, this.Size())
+	for n := this.top; n != nil; n = n.next {
+		answer = append(answer, n.value)
+	}
+	return answer
+}
+
+// Equals returns true if other holds the same number of values in the same order
+// and equals returns true for each pair of values.
+func (this *stackImpl[T]) Equals(other Stack[T], equals EqualsFunc[T]) bool {
+	return this.Size() == other.Size() && listsEqual(this.Iterate(), other.Iterate(), equals)
+}
+
+func (this *listIteratorImpl[T]) Next() bool {
+	if this.current == nil && this.reversed != nil {
+		this.current = this.reversed.reverseOnto(nil)
+		this.reversed = nil
+	}
+	if this.current == nil {
+		return false
+	}
+	this.value = this.current.value
+	this.current = this.current.next
+	return true
+}
+
+func (this *listIteratorImpl[T]) Get() T {
+	return this.value
+}
+
+func (this *stackImpl[T]) checkInvariants(report reporter) {
+	this.top.checkInvariants(report)
+}
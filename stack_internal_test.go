@@ -0,0 +1,56 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+)
+
+// verifyRangedTwice reports an error unless all visits the expected values each
+// time it is ranged over.
+func verifyRangedTwice(t *testing.T, all iter.Seq[int], expected []int) {
+	if !slices.Equal(slices.Collect(all), expected) || !slices.Equal(slices.Collect(all), expected) {
+		t.Error("All did not visit the same values when ranged twice")
+	}
+}
+
+func TestStack(t *testing.T) {
+	s := CreateStack[int]()
+	if _, ok := s.Peek(); ok {
+		t.Error("Peek returned true for empty stack")
+	}
+	for i := 0; i < 100; i++ {
+		s = s.Push(i)
+	}
+	s.checkInvariants(createReporter(t))
+	before := s
+	for i := 99; i >= 50; i-- {
+		var value int
+		var ok bool
+		s, value, ok = s.Pop()
+		if !ok || value != i {
+			t.Error(fmt.Sprintf("Pop mismatch: expected=%d actual=%d ok=%v", i, value, ok))
+		}
+	}
+	s.checkInvariants(createReporter(t))
+	if s.Size() != 50 || before.Size() != 100 {
+		t.Error(fmt.Sprintf("size mismatch: expected=50,100 actual=%d,%d", s.Size(), before.Size()))
+	}
+	if top, _ := s.Peek(); top != 49 {
+		t.Error(fmt.Sprintf("Peek mismatch: expected=49 actual=%d", top))
+	}
+	if values := s.ToSlice(); values[0] != 49 || values[49] != 0 {
+		t.Error(fmt.Sprintf("ToSlice order mismatch: %v", values[:3]))
+	}
+	verifyRangedTwice(t, s.All(), s.ToSlice())
+
+	equals := func(a int, b int) bool { return a == b }
+	other := CreateStack[int]()
+	for _, value := range slices.Backward(s.ToSlice()) {
+		other = other.Push(value)
+	}
+	if !s.Equals(other, equals) || s.Equals(before, equals) || s.Equals(other.Push(1), equals) {
+		t.Error("Equals returned incorrect result")
+	}
+}
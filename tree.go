@@ -0,0 +1,418 @@
+package immutableMap
+
+import "fmt"
+
+// CompareFunc returns a negative number if a sorts before b, a positive number if
+// a sorts after b, and zero if they are equal.
+type CompareFunc[K any] func(a K, b K) int
+
+// treeNode is a node of a persistent left-leaning red-black tree.  A node is never
+// modified once it is reachable from a published tree.  Every change copies the
+// nodes along its path from the root and the helpers below that rebalance the
+// tree only modify nodes that were copied by the current change.  size is the
+// number of keys in the subtree rooted at the node.
+type treeNode[K any, V any] struct {
+	left  *treeNode[K, V]
+	right *treeNode[K, V]
+	key   K
+	value V
+	size  int
+	red   bool
+}
+
+// treeIterator walks a tree in key order, or in reverse order when descending is
+// true, using a stack of the nodes whose keys have not been visited yet.  When
+// hasLimit is true iteration stops at the first key that is not before limit, or
+// when descending at the first key that is before limit.
+type treeIterator[K any, V any] struct {
+	stack      []*treeNode[K, V]
+	compare    CompareFunc[K]
+	descending bool
+	limit      K
+	hasLimit   bool
+	key        K
+	value      V
+}
+
+func isRed[K any, V any](n *treeNode[K, V]) bool {
+	return n != nil && n.red
+}
+
+func treeSize[K any, V any](n *treeNode[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (this *treeNode[K, V]) resize() {
+	this.size = 1 + treeSize(this.left) + treeSize(this.right)
+}
+
+func (this *treeNode[K, V]) clone() *treeNode[K, V] {
+	newNode := *this
+	return &newNode
+}
+
+func (this *treeNode[K, V]) find(key K, compare CompareFunc[K]) *treeNode[K, V] {
+	n := this
+	for n != nil {
+		c := compare(key, n.key)
+		if c < 0 {
+			n = n.left
+		} else if c > 0 {
+			n = n.right
+		} else {
+			return n
+		}
+	}
+	return nil
+}
+
+// assign returns a tree containing the key and value along with the change in the
+// number of keys.  The receiver is returned if the key already has the value.
+func (this *treeNode[K, V]) assign(key K, value V, compare CompareFunc[K]) (*treeNode[K, V], int) {
+	if this == nil {
+		return &treeNode[K, V]{key: key, value: value, size: 1, red: true}, 1
+	}
+
+	c := compare(key, this.key)
+	if c < 0 {
+		newLeft, delta := this.left.assign(key, value, compare)
+		if newLeft == this.left {
+			return this, 0
+		}
+		h := this.clone()
+		h.left = newLeft
+		h.resize()
+		return h.balance(), delta
+	} else if c > 0 {
+		newRight, delta := this.right.assign(key, value, compare)
+		if newRight == this.right {
+			return this, 0
+		}
+		h := this.clone()
+		h.right = newRight
+		h.resize()
+		return h.balance(), delta
+	} else if sameValue(this.value, value) {
+		return this, 0
+	} else {
+		h := this.clone()
+		h.value = value
+		return h, 0
+	}
+}
+
+// delete returns a tree without the key.  The key must be present in the tree.
+func (this *treeNode[K, V]) delete(key K, compare CompareFunc[K]) *treeNode[K, V] {
+	h := this.clone()
+	if compare(key, h.key) < 0 {
+		if !isRed(h.left) && !isRed(h.left.left) {
+			h = h.moveRedLeft()
+		}
+		h.left = h.left.delete(key, compare)
+	} else {
+		if isRed(h.left) {
+			h = h.rotateRight()
+		}
+		if compare(key, h.key) == 0 && h.right == nil {
+			return nil
+		}
+		if !isRed(h.right) && !isRed(h.right.left) {
+			h = h.moveRedRight()
+		}
+		if compare(key, h.key) == 0 {
+			successor := h.right.min()
+			h.key = successor.key
+			h.value = successor.value
+			h.right = h.right.deleteMin()
+		} else {
+			h.right = h.right.delete(key, compare)
+		}
+	}
+	h.resize()
+	return h.balance()
+}
+
+// remove returns the tree without the key starting from the root of a tree.  The
+// key must be present in the tree.
+func (this *treeNode[K, V]) remove(key K, compare CompareFunc[K]) *treeNode[K, V] {
+	root := this.clone()
+	if !isRed(root.left) && !isRed(root.right) {
+		root.red = true
+	}
+	return root.delete(key, compare)
+}
+
+// blacken returns a root with the same keys whose color is black.
+func (this *treeNode[K, V]) blacken() *treeNode[K, V] {
+	if !isRed(this) {
+		return this
+	}
+	root := this.clone()
+	root.red = false
+	return root
+}
+
+func (this *treeNode[K, V]) deleteMin() *treeNode[K, V] {
+	if this.left == nil {
+		return nil
+	}
+	h := this.clone()
+	if !isRed(h.left) && !isRed(h.left.left) {
+		h = h.moveRedLeft()
+	}
+	h.left = h.left.deleteMin()
+	h.resize()
+	return h.balance()
+}
+
+// The methods below implement the rebalancing steps of a left-leaning red-black
+// tree.  Each expects a receiver copied by the current change and copies any other
+// node it modifies.
+
+func (this *treeNode[K, V]) rotateLeft() *treeNode[K, V] {
+	x := this.right.clone()
+	this.right = x.left
+	x.left = this
+	x.red = this.red
+	this.red = true
+	x.size = this.size
+	this.resize()
+	return x
+}
+
+func (this *treeNode[K, V]) rotateRight() *treeNode[K, V] {
+	x := this.left.clone()
+	this.left = x.right
+	x.right = this
+	x.red = this.red
+	this.red = true
+	x.size = this.size
+	this.resize()
+	return x
+}
+
+func (this *treeNode[K, V]) flipColors() {
+	this.red = !this.red
+	this.left = this.left.clone()
+	this.left.red = !this.left.red
+	this.right = this.right.clone()
+	this.right.red = !this.right.red
+}
+
+func (this *treeNode[K, V]) moveRedLeft() *treeNode[K, V] {
+	h := this
+	h.flipColors()
+	if isRed(h.right.left) {
+		h.right = h.right.rotateRight()
+		h = h.rotateLeft()
+		h.flipColors()
+	}
+	return h
+}
+
+func (this *treeNode[K, V]) moveRedRight() *treeNode[K, V] {
+	h := this
+	h.flipColors()
+	if isRed(h.left.left) {
+		h = h.rotateRight()
+		h.flipColors()
+	}
+	return h
+}
+
+func (this *treeNode[K, V]) balance() *treeNode[K, V] {
+	h := this
+	if isRed(h.right) && !isRed(h.left) {
+		h = h.rotateLeft()
+	}
+	if isRed(h.left) && isRed(h.left.left) {
+		h = h.rotateRight()
+	}
+	if isRed(h.left) && isRed(h.right) {
+		h.flipColors()
+	}
+	return h
+}
+
+func (this *treeNode[K, V]) min() *treeNode[K, V] {
+	n := this
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func (this *treeNode[K, V]) max() *treeNode[K, V] {
+	n := this
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// floor returns the node with the greatest key less than or equal to key.
+func (this *treeNode[K, V]) floor(key K, compare CompareFunc[K]) *treeNode[K, V] {
+	var answer *treeNode[K, V]
+	for n := this; n != nil; {
+		c := compare(key, n.key)
+		if c == 0 {
+			return n
+		} else if c < 0 {
+			n = n.left
+		} else {
+			answer = n
+			n = n.right
+		}
+	}
+	return answer
+}
+
+// ceiling returns the node with the least key greater than or equal to key.
+func (this *treeNode[K, V]) ceiling(key K, compare CompareFunc[K]) *treeNode[K, V] {
+	var answer *treeNode[K, V]
+	for n := this; n != nil; {
+		c := compare(key, n.key)
+		if c == 0 {
+			return n
+		} else if c > 0 {
+			n = n.right
+		} else {
+			answer = n
+			n = n.left
+		}
+	}
+	return answer
+}
+
+func (this *treeNode[K, V]) forEach(v MapVisitor[K, V]) {
+	if this != nil {
+		this.left.forEach(v)
+		v(this.key, this.value)
+		this.right.forEach(v)
+	}
+}
+
+// rank returns the number of keys in the tree that are less than key.
+func (this *treeNode[K, V]) rank(key K, compare CompareFunc[K]) int {
+	answer := 0
+	for n := this; n != nil; {
+		c := compare(key, n.key)
+		if c < 0 {
+			n = n.left
+		} else if c > 0 {
+			answer += treeSize(n.left) + 1
+			n = n.right
+		} else {
+			return answer + treeSize(n.left)
+		}
+	}
+	return answer
+}
+
+// selectIndex returns the node whose key has the given rank or nil if the index is
+// out of range.
+func (this *treeNode[K, V]) selectIndex(index int) *treeNode[K, V] {
+	for n := this; n != nil; {
+		leftSize := treeSize(n.left)
+		if index < leftSize {
+			n = n.left
+		} else if index > leftSize {
+			index -= leftSize + 1
+			n = n.right
+		} else {
+			return n
+		}
+	}
+	return nil
+}
+
+// createIterator returns an iterator positioned before the first key that is not
+// before start.  When descending it is instead positioned before the last key that
+// is before start.  If hasStart is false iteration begins at the first or last key.
+func (this *treeNode[K, V]) createIterator(compare CompareFunc[K], start K, hasStart bool, descending bool) *treeIterator[K, V] {
+	answer := &treeIterator[K, V]{compare: compare, descending: descending}
+	for n := this; n != nil; {
+		if !descending && hasStart && compare(n.key, start) < 0 {
+			n = n.right
+		} else if descending && hasStart && compare(n.key, start) >= 0 {
+			n = n.left
+		} else {
+			answer.stack = append(answer.stack, n)
+			n = n.child(!descending)
+		}
+	}
+	return answer
+}
+
+// child returns the left child if left is true or the right child otherwise.
+func (this *treeNode[K, V]) child(left bool) *treeNode[K, V] {
+	if left {
+		return this.left
+	}
+	return this.right
+}
+
+// withLimit sets the key at which the iterator stops and returns the iterator.
+func (this *treeIterator[K, V]) withLimit(limit K) *treeIterator[K, V] {
+	this.limit = limit
+	this.hasLimit = true
+	return this
+}
+
+func (this *treeIterator[K, V]) Next() bool {
+	if len(this.stack) == 0 {
+		return false
+	}
+	n := this.stack[len(this.stack)-1]
+	if this.hasLimit {
+		if c := this.compare(n.key, this.limit); (!this.descending && c >= 0) || (this.descending && c < 0) {
+			this.stack = nil
+			return false
+		}
+	}
+	this.stack = this.stack[:len(this.stack)-1]
+	for child := n.child(this.descending); child != nil; child = child.child(!this.descending) {
+		this.stack = append(this.stack, child)
+	}
+	this.key, this.value = n.key, n.value
+	return true
+}
+
+func (this *treeIterator[K, V]) Get() (K, V) {
+	return this.key, this.value
+}
+
+// checkInvariants verifies the ordering and red-black properties of the tree and
+// returns the number of black nodes on every path to a leaf.
+func (this *treeNode[K, V]) checkInvariants(compare CompareFunc[K], report reporter) int {
+	if this == nil {
+		return 1
+	}
+	if this.left != nil && compare(this.left.key, this.key) >= 0 {
+		report(fmt.Sprintf("left child out of order: key=%v left=%v", this.key, this.left.key))
+	}
+	if this.right != nil && compare(this.right.key, this.key) <= 0 {
+		report(fmt.Sprintf("right child out of order: key=%v right=%v", this.key, this.right.key))
+	}
+	if isRed(this.right) {
+		report(fmt.Sprintf("red right child detected: key=%v", this.key))
+	}
+	if this.red && isRed(this.left) {
+		report(fmt.Sprintf("consecutive red nodes detected: key=%v", this.key))
+	}
+	if size := 1 + treeSize(this.left) + treeSize(this.right); size != this.size {
+		report(fmt.Sprintf("subtree size mismatch: key=%v expected=%d actual=%d", this.key, size, this.size))
+	}
+	leftHeight := this.left.checkInvariants(compare, report)
+	rightHeight := this.right.checkInvariants(compare, report)
+	if leftHeight != rightHeight {
+		report(fmt.Sprintf("black height mismatch: key=%v left=%d right=%d", this.key, leftHeight, rightHeight))
+	}
+	if this.red {
+		return leftHeight
+	}
+	return leftHeight + 1
+}
@@ -0,0 +1,565 @@
+package immutableMap
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// Vector is an immutable sequence of values indexed from zero.  Every Vector is also
+// a Collection of its values in index order.  Methods that accept an index panic if
+// it is out of range just as indexing a slice would.
+type Vector[T any] interface {
+	Get(index int) T
+	Set(index int, value T) Vector[T]
+	Append(value T) Vector[T]
+	Pop() (Vector[T], T, bool)
+	Slice(from int, to int) Vector[T]
+	Concat(other Vector[T]) Vector[T]
+	Size() int
+	Iterate() Iterator[T]
+	ForEach(v SetVisitor[T])
+	All() iter.Seq[T]
+	ToSlice() []T
+	checkInvariants(report reporter)
+}
+
+const vectorBits = 5
+const vectorWidth = 1 << vectorBits
+
+// vectorExtraSteps is the number of nodes beyond the fewest that could hold their
+// slots that concatenation allows at each level before it rebalances them.
+const vectorExtraSteps = 2
+
+// vectorNode is a node of a relaxed radix balanced tree.  Leaves hold up to 32
+// values and branches up to 32 children, and every leaf is at the same depth.  The
+// shift of a node is the number of index bits consumed by its descendants so each
+// child of a branch can hold at most 1<<shift values.  When every child but the
+// last is full the child holding an index is found directly from its bits.
+// Otherwise sizes holds the running total of the sizes of the children.  Nodes are
+// never modified once created so vectors share every node they did not change.
+type vectorNode[T any] struct {
+	size     int
+	values   []T
+	children []*vectorNode[T]
+	sizes    []int
+}
+
+// vectorImpl holds the root of the tree, which is nil for an empty vector, and its
+// shift.  A root branch always has more than one child.
+type vectorImpl[T any] struct {
+	root  *vectorNode[T]
+	shift uint
+}
+
+type vectorIteratorFrame[T any] struct {
+	node *vectorNode[T]
+	next int
+}
+
+// vectorIteratorImpl visits the values of one leaf at a time using a stack of the
+// branches above that leaf and the index of the next child to visit in each.
+type vectorIteratorImpl[T any] struct {
+	stack  []vectorIteratorFrame[T]
+	values []T
+	index  int
+	value  T
+}
+
+// NewVector creates an empty Vector.
+func NewVector[T any]() Vector[T] {
+	return &vectorImpl[T]{}
+}
+
+func newVectorLeaf[T any](values []T) *vectorNode[T] {
+	return &vectorNode[T]{size: len(values), values: values}
+}
+
+// newVectorBranch creates a branch containing the children.  A size table is only
+// created if some child other than the last is not full.
+func newVectorBranch[T any](children []*vectorNode[T], shift uint) *vectorNode[T] {
+	answer := &vectorNode[T]{children: children}
+	regular := true
+	for i, child := range children {
+		answer.size += child.size
+		if i < len(children)-1 && child.size != 1<<shift {
+			regular = false
+		}
+	}
+	if !regular {
+		answer.sizes = make([]int, len(children))
+		total := 0
+		for i, child := range children {
+			total += child.size
+			answer.sizes[i] = total
+		}
+	}
+	return answer
+}
+
+// locate returns the index of the child containing index and the position of index
+// within that child.
+func (this *vectorNode[T]) locate(index int, shift uint) (int, int) {
+	child := index >> shift
+	if this.sizes == nil {
+		return child, index - child<<shift
+	}
+	for this.sizes[child] <= index {
+		child++
+	}
+	if child == 0 {
+		return 0, index
+	}
+	return child, index - this.sizes[child-1]
+}
+
+func (this *vectorNode[T]) get(index int, shift uint) T {
+	n := this
+	for ; shift > 0; shift -= vectorBits {
+		child, position := n.locate(index, shift)
+		n = n.children[child]
+		index = position
+	}
+	return n.values[index]
+}
+
+// set returns a tree with the value at index replaced.  The receiver is returned
+// if the index already has the value.
+func (this *vectorNode[T]) set(index int, shift uint, value T) *vectorNode[T] {
+	if shift == 0 {
+		if sameValue(this.values[index], value) {
+			return this
+		}
+		values := slices.Clone(this.values)
+		values[index] = value
+		return newVectorLeaf(values)
+	}
+	child, position := this.locate(index, shift)
+	newChild := this.children[child].set(position, shift-vectorBits, value)
+	if newChild == this.children[child] {
+		return this
+	}
+	newNode := *this
+	newNode.children = slices.Clone(this.children)
+	newNode.children[child] = newChild
+	return &newNode
+}
+
+// take returns a tree containing the first count values.  count must be greater
+// than zero.
+func (this *vectorNode[T]) take(count int, shift uint) *vectorNode[T] {
+	if count == this.size {
+		return this
+	}
+	if shift == 0 {
+		return newVectorLeaf(slices.Clip(this.values[:count]))
+	}
+	child, position := this.locate(count-1, shift)
+	children := append(slices.Clip(this.children[:child]), this.children[child].take(position+1, shift-vectorBits))
+	return newVectorBranch(children, shift)
+}
+
+// drop returns a tree without the first count values.  count must be less than the
+// size of the tree.
+func (this *vectorNode[T]) drop(count int, shift uint) *vectorNode[T] {
+	if count == 0 {
+		return this
+	}
+	if shift == 0 {
+		return newVectorLeaf(this.values[count:])
+	}
+	child, position := this.locate(count, shift)
+	children := append([]*vectorNode[T]{this.children[child].drop(position, shift-vectorBits)}, this.children[child+1:]...)
+	return newVectorBranch(children, shift)
+}
+
+// append returns a tree with value added after its last value or nil if the last
+// node at every level along the right edge is full.
+func (this *vectorNode[T]) append(shift uint, value T) *vectorNode[T] {
+	if shift == 0 {
+		if len(this.values) == vectorWidth {
+			return nil
+		}
+		values := make([]T, len(this.values), len(this.values)+1)
+		copy(values, this.values)
+		return newVectorLeaf(append(values, value))
+	}
+	last := len(this.children) - 1
+	if newChild := this.children[last].append(shift-vectorBits, value); newChild != nil {
+		newNode := *this
+		newNode.size++
+		newNode.children = slices.Clone(this.children)
+		newNode.children[last] = newChild
+		if this.sizes != nil {
+			newNode.sizes = slices.Clone(this.sizes)
+			newNode.sizes[last]++
+		}
+		return &newNode
+	} else if len(this.children) < vectorWidth {
+		children := make([]*vectorNode[T], len(this.children), len(this.children)+1)
+		copy(children, this.children)
+		return newVectorBranch(append(children, newVectorPath(shift-vectorBits, value)), shift)
+	}
+	return nil
+}
+
+// newVectorPath creates a tree with the given shift containing only value.
+func newVectorPath[T any](shift uint, value T) *vectorNode[T] {
+	answer := newVectorLeaf([]T{value})
+	for level := uint(vectorBits); level <= shift; level += vectorBits {
+		answer = &vectorNode[T]{size: 1, children: []*vectorNode[T]{answer}}
+	}
+	return answer
+}
+
+// concatVectorNodes returns the nodes of a tree containing the values of left
+// followed by the values of right.  Only the nodes along the right edge of left
+// and the left edge of right, and any nodes beside them that are rebalanced, are
+// copied.  The result holds one or two nodes with the greater of the two shifts.
+func concatVectorNodes[T any](left *vectorNode[T], leftShift uint, right *vectorNode[T], rightShift uint) []*vectorNode[T] {
+	if leftShift > rightShift {
+		last := len(left.children) - 1
+		merged := concatVectorNodes(left.children[last], leftShift-vectorBits, right, rightShift)
+		return splitVectorChildren(append(slices.Clip(left.children[:last]), merged...), leftShift)
+	} else if leftShift < rightShift {
+		merged := concatVectorNodes(left, leftShift, right.children[0], rightShift-vectorBits)
+		return splitVectorChildren(append(merged, right.children[1:]...), rightShift)
+	} else if leftShift > 0 {
+		last := len(left.children) - 1
+		merged := concatVectorNodes(left.children[last], leftShift-vectorBits, right.children[0], rightShift-vectorBits)
+		children := make([]*vectorNode[T], 0, last+len(merged)+len(right.children)-1)
+		children = append(children, left.children[:last]...)
+		children = append(children, merged...)
+		children = append(children, right.children[1:]...)
+		return splitVectorChildren(children, leftShift)
+	} else if len(left.values) == vectorWidth {
+		return []*vectorNode[T]{left, right}
+	} else {
+		values := make([]T, 0, len(left.values)+len(right.values))
+		values = append(values, left.values...)
+		values = append(values, right.values...)
+		if len(values) <= vectorWidth {
+			return []*vectorNode[T]{newVectorLeaf(values)}
+		}
+		return []*vectorNode[T]{newVectorLeaf(values[:vectorWidth:vectorWidth]), newVectorLeaf(values[vectorWidth:])}
+	}
+}
+
+// splitVectorChildren rebalances the children and returns one branch containing
+// them or two branches if there are too many children for one.
+func splitVectorChildren[T any](children []*vectorNode[T], shift uint) []*vectorNode[T] {
+	children = rebalanceVectorNodes(children, shift-vectorBits)
+	if len(children) <= vectorWidth {
+		return []*vectorNode[T]{newVectorBranch(children, shift)}
+	}
+	return []*vectorNode[T]{newVectorBranch(children[:vectorWidth:vectorWidth], shift), newVectorBranch(children[vectorWidth:], shift)}
+}
+
+// slots returns the number of values in a leaf or children in a branch.
+func (this *vectorNode[T]) slots() int {
+	if this.children == nil {
+		return len(this.values)
+	}
+	return len(this.children)
+}
+
+// rebalanceVectorNodes returns nodes holding the same slots as the nodes, all of
+// which have the given shift.  Nothing is done unless there are more than
+// vectorExtraSteps nodes beyond the fewest that could hold every slot.  Otherwise,
+// starting from the first node that is not full, slots are moved toward the front
+// until one node is emptied and removed, and this repeats until few enough nodes
+// remain.  Nodes whose slots did not change are reused.
+func rebalanceVectorNodes[T any](nodes []*vectorNode[T], shift uint) []*vectorNode[T] {
+	plan := make([]int, len(nodes))
+	total := 0
+	for i, n := range nodes {
+		plan[i] = n.slots()
+		total += plan[i]
+	}
+	optimal := (total + vectorWidth - 1) / vectorWidth
+	count := len(plan)
+	if count <= optimal+vectorExtraSteps {
+		return nodes
+	}
+	for i := 0; count > optimal+vectorExtraSteps; i-- {
+		for plan[i] == vectorWidth {
+			i++
+		}
+		for remaining := plan[i]; remaining > 0; i++ {
+			size := min(remaining+plan[i+1], vectorWidth)
+			remaining += plan[i+1] - size
+			plan[i] = size
+		}
+		copy(plan[i:count-1], plan[i+1:count])
+		count--
+	}
+
+	answer := make([]*vectorNode[T], 0, count)
+	source, offset := 0, 0
+	for _, size := range plan[:count] {
+		if offset == 0 && nodes[source].slots() == size {
+			answer = append(answer, nodes[source])
+			source++
+		} else if shift == 0 {
+			values := make([]T, 0, size)
+			for len(values) < size {
+				n := nodes[source]
+				end := min(offset+size-len(values), len(n.values))
+				values = append(values, n.values[offset:end]...)
+				source, offset = advanceVectorSource(source, end, len(n.values))
+			}
+			answer = append(answer, newVectorLeaf(values))
+		} else {
+			children := make([]*vectorNode[T], 0, size)
+			for len(children) < size {
+				n := nodes[source]
+				end := min(offset+size-len(children), len(n.children))
+				children = append(children, n.children[offset:end]...)
+				source, offset = advanceVectorSource(source, end, len(n.children))
+			}
+			answer = append(answer, newVectorBranch(children, shift))
+		}
+	}
+	return answer
+}
+
+// advanceVectorSource returns the position following end in the source node,
+// moving to the next node once every slot has been used.
+func advanceVectorSource(source int, end int, slots int) (int, int) {
+	if end == slots {
+		return source + 1, 0
+	}
+	return source, end
+}
+
+// vectorFromNodes returns a vector whose root holds the nodes, each of which has
+// the given shift.  Branches with a single child are removed from the top of the
+// tree.
+func vectorFromNodes[T any](nodes []*vectorNode[T], shift uint) *vectorImpl[T] {
+	root := nodes[0]
+	if len(nodes) > 1 {
+		shift += vectorBits
+		root = newVectorBranch(nodes, shift)
+	}
+	for shift > 0 && len(root.children) == 1 {
+		root = root.children[0]
+		shift -= vectorBits
+	}
+	return &vectorImpl[T]{root: root, shift: shift}
+}
+
+func (this *vectorImpl[T]) checkIndex(index int, size int) {
+	if index < 0 || index >= size {
+		panic(fmt.Sprintf("vector index out of range: index=%d size=%d", index, size))
+	}
+}
+
+func (this *vectorImpl[T]) Get(index int) T {
+	this.checkIndex(index, this.Size())
+	return this.root.get(index, this.shift)
+}
+
+// Set returns a vector with the value at index replaced.  The receiver is returned
+// if the index already has the value.
+func (this *vectorImpl[T]) Set(index int, value T) Vector[T] {
+	this.checkIndex(index, this.Size())
+	newRoot := this.root.set(index, this.shift, value)
+	if newRoot == this.root {
+		return this
+	}
+	return &vectorImpl[T]{root: newRoot, shift: this.shift}
+}
+
+// Append returns a vector with value added after the last value.  Only the nodes
+// along the right edge are copied.  A node is only added when the last node at its
+// level has no free slots so appending cannot unbalance the tree.
+func (this *vectorImpl[T]) Append(value T) Vector[T] {
+	if this.root == nil {
+		return &vectorImpl[T]{root: newVectorLeaf([]T{value})}
+	} else if newRoot := this.root.append(this.shift, value); newRoot != nil {
+		return &vectorImpl[T]{root: newRoot, shift: this.shift}
+	}
+	shift := this.shift + vectorBits
+	return &vectorImpl[T]{root: newVectorBranch([]*vectorNode[T]{this.root, newVectorPath(shift-vectorBits, value)}, shift), shift: shift}
+}
+
+// Pop returns a vector without its last value along with that value.  The final
+// result is false if the vector is empty.
+func (this *vectorImpl[T]) Pop() (Vector[T], T, bool) {
+	size := this.Size()
+	if size == 0 {
+		var zero T
+		return this, zero, false
+	}
+	return this.Slice(0, size-1), this.root.get(size-1, this.shift), true
+}
+
+// Slice returns a vector containing the values from index from up to but not
+// including index to.
+func (this *vectorImpl[T]) Slice(from int, to int) Vector[T] {
+	size := this.Size()
+	if from < 0 || to > size || from > to {
+		panic(fmt.Sprintf("vector slice out of range: from=%d to=%d size=%d", from, to, size))
+	}
+	if from == 0 && to == size {
+		return this
+	} else if from == to {
+		return &vectorImpl[T]{}
+	}
+	newRoot := this.root.take(to, this.shift).drop(from, this.shift)
+	return vectorFromNodes([]*vectorNode[T]{newRoot}, this.shift)
+}
+
+// Concat returns a vector containing the values of this vector followed by the
+// values of other.
+func (this *vectorImpl[T]) Concat(other Vector[T]) Vector[T] {
+	otherImpl, ok := other.(*vectorImpl[T])
+	if !ok {
+		answer := Vector[T](this)
+		for value := range other.All() {
+			answer = answer.Append(value)
+		}
+		return answer
+	} else if otherImpl.root == nil {
+		return this
+	} else if this.root == nil {
+		return otherImpl
+	}
+	return vectorFromNodes(concatVectorNodes(this.root, this.shift, otherImpl.root, otherImpl.shift), max(this.shift, otherImpl.shift))
+}
+
+func (this *vectorImpl[T]) Size() int {
+	if this.root == nil {
+		return 0
+	}
+	return this.root.size
+}
+
+func (this *vectorImpl[T]) Iterate() Iterator[T] {
+	answer := &vectorIteratorImpl[T]{}
+	if this.root != nil {
+		answer.descend(this.root)
+	}
+	return answer
+}
+
+func (this *vectorImpl[T]) ForEach(v SetVisitor[T]) {
+	for i := this.Iterate(); i.Next(); {
+		v(i.Get())
+	}
+}
+
+// All returns an iterator over the values of the vector in index order for use
+// with range.
+func (this *vectorImpl[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := this.Iterate(); i.Next(); {
+			if !yield(i.Get()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *vectorImpl[T]) ToSlice() []T {
+	answer := make([]T, 0, this.Size())
+	for i := this.Iterate(); i.Next(); {
+		answer = append(answer, i.Get())
+	}
+	return answer
+}
+
+// descend pushes the branches along the left edge of n and positions the iterator
+// at the start of its first leaf.
+func (this *vectorIteratorImpl[T]) descend(n *vectorNode[T]) {
+	for n.children != nil {
+		this.stack = append(this.stack, vectorIteratorFrame[T]{node: n, next: 1})
+		n = n.children[0]
+	}
+	this.values = n.values
+	this.index = 0
+}
+
+func (this *vectorIteratorImpl[T]) Next() bool {
+	for this.index >= len(this.values) {
+		for len(this.stack) > 0 && this.stack[len(this.stack)-1].next >= len(this.stack[len(this.stack)-1].node.children) {
+			this.stack = this.stack[:len(this.stack)-1]
+		}
+		if len(this.stack) == 0 {
+			this.values = nil
+			return false
+		}
+		top := &this.stack[len(this.stack)-1]
+		child := top.node.children[top.next]
+		top.next++
+		this.descend(child)
+	}
+	this.value = this.values[this.index]
+	this.index++
+	return true
+}
+
+func (this *vectorIteratorImpl[T]) Get() T {
+	return this.value
+}
+
+func (this *vectorImpl[T]) checkInvariants(report reporter) {
+	if this.root == nil {
+		if this.shift != 0 {
+			report(fmt.Sprintf("empty vector has non-zero shift: shift=%d", this.shift))
+		}
+		return
+	}
+	if this.shift > 0 && len(this.root.children) == 1 {
+		report("root with single child detected")
+	}
+	this.root.checkInvariants(this.shift, report)
+	index := 0
+	for i := this.Iterate(); i.Next(); {
+		if actual := this.Get(index); !sameValue(i.Get(), actual) {
+			report(fmt.Sprintf("Get returned incorrect result: index=%d expected=%v actual=%v", index, i.Get(), actual))
+		}
+		index++
+	}
+	if index != this.Size() {
+		report(fmt.Sprintf("Size() does not match number of values in iterator: expected=%d actual=%d", this.Size(), index))
+	}
+}
+
+func (this *vectorNode[T]) checkInvariants(shift uint, report reporter) {
+	if shift == 0 {
+		if this.children != nil {
+			report("branch found at leaf depth")
+		} else if len(this.values) == 0 || len(this.values) > vectorWidth {
+			report(fmt.Sprintf("invalid leaf length: length=%d", len(this.values)))
+		} else if this.size != len(this.values) {
+			report(fmt.Sprintf("leaf size mismatch: expected=%d actual=%d", len(this.values), this.size))
+		}
+		return
+	}
+	if this.values != nil {
+		report(fmt.Sprintf("leaf found above leaf depth: shift=%d", shift))
+	}
+	if len(this.children) == 0 || len(this.children) > vectorWidth {
+		report(fmt.Sprintf("invalid branch length: shift=%d length=%d", shift, len(this.children)))
+		return
+	}
+	if this.sizes != nil && len(this.sizes) != len(this.children) {
+		report(fmt.Sprintf("size table length mismatch: expected=%d actual=%d", len(this.children), len(this.sizes)))
+		return
+	}
+	total := 0
+	for i, child := range this.children {
+		child.checkInvariants(shift-vectorBits, report)
+		total += child.size
+		if this.sizes != nil && this.sizes[i] != total {
+			report(fmt.Sprintf("size table mismatch: index=%d expected=%d actual=%d", i, total, this.sizes[i]))
+		}
+		if this.sizes == nil && i < len(this.children)-1 && child.size != 1<<shift {
+			report(fmt.Sprintf("partial child in regular branch: shift=%d index=%d size=%d", shift, i, child.size))
+		}
+	}
+	if total != this.size {
+		report(fmt.Sprintf("branch size mismatch: expected=%d actual=%d", total, this.size))
+	}
+}
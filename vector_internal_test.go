@@ -0,0 +1,223 @@
+package immutableMap
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func vectorOf(values []int) Vector[int] {
+	v := NewVector[int]()
+	for _, value := range values {
+		v = v.Append(value)
+	}
+	return v
+}
+
+func verifyVector(t *testing.T, v Vector[int], expected []int) {
+	v.checkInvariants(createReporter(t))
+	if v.Size() != len(expected) {
+		t.Error(fmt.Sprintf("expected size %d but got %d", len(expected), v.Size()))
+		return
+	}
+	for i, value := range expected {
+		if actual := v.Get(i); actual != value {
+			t.Error(fmt.Sprintf("Get mismatch: index=%d expected=%d actual=%d", i, value, actual))
+			return
+		}
+	}
+	if !slices.Equal(v.ToSlice(), expected) {
+		t.Error("ToSlice does not match expected values")
+	}
+}
+
+func TestVectorAppend(t *testing.T) {
+	v := NewVector[int]()
+	var expected []int
+	for i := 0; i < 40000; i++ {
+		v = v.Append(i)
+		expected = append(expected, i)
+		if i%4999 == 0 {
+			verifyVector(t, v, expected)
+		}
+	}
+	verifyVector(t, v, expected)
+	if v.(*vectorImpl[int]).root.sizes != nil {
+		t.Error("appending created a size table")
+	}
+	impl := v.(*vectorImpl[int])
+	if v.Append(-1).(*vectorImpl[int]).root.children[0] != impl.root.children[0] {
+		t.Error("appending copied a node off the right edge")
+	}
+	// each level copies one node and its slice plus one for the vector itself
+	limit := float64(2*(impl.shift/vectorBits+1) + 1)
+	if allocs := testing.AllocsPerRun(100, func() { v.Append(-1) }); allocs > limit {
+		t.Error(fmt.Sprintf("too many allocations for append: limit=%v actual=%v", limit, allocs))
+	}
+
+	before := v
+	v = v.Set(12345, -1)
+	expected[12345] = -1
+	verifyVector(t, v, expected)
+	if before.Get(12345) != 12345 {
+		t.Error("Set modified the original vector")
+	}
+	if v.Set(12345, -1) != v {
+		t.Error("unchanged vector was copied")
+	}
+
+	for len(expected) > 0 {
+		var value int
+		var ok bool
+		v, value, ok = v.Pop()
+		if !ok || value != expected[len(expected)-1] {
+			t.Error(fmt.Sprintf("Pop mismatch: expected=%d actual=%d ok=%v", expected[len(expected)-1], value, ok))
+		}
+		expected = expected[:len(expected)-1]
+		if len(expected)%4999 == 0 {
+			verifyVector(t, v, expected)
+		}
+	}
+	if _, _, ok := v.Pop(); ok {
+		t.Error("Pop returned true for empty vector")
+	}
+	verifyVector(t, before, before.ToSlice())
+}
+
+func TestVectorSliceAndConcat(t *testing.T) {
+	random := rand.New(rand.NewSource(21))
+	v := NewVector[int]()
+	var expected []int
+	for i := 0; i < 500; i++ {
+		switch random.Intn(5) {
+		case 0:
+			from := random.Intn(len(expected) + 1)
+			to := from + random.Intn(len(expected)-from+1)
+			v = v.Slice(from, to)
+			expected = slices.Clone(expected[from:to])
+		case 1:
+			size := random.Intn(2000)
+			other := make([]int, size)
+			for j := range other {
+				other[j] = random.Int()
+			}
+			v = v.Concat(vectorOf(other))
+			expected = append(expected, other...)
+		case 2:
+			v = v.Concat(v)
+			expected = append(expected, expected...)
+			if len(expected) > 100000 {
+				v = v.Slice(len(expected)-50000, len(expected))
+				expected = slices.Clone(expected[len(expected)-50000:])
+			}
+		case 3:
+			if len(expected) > 0 {
+				index := random.Intn(len(expected))
+				v = v.Set(index, i)
+				expected[index] = i
+			}
+		default:
+			v = v.Append(i)
+			expected = append(expected, i)
+		}
+		if i%25 == 0 {
+			verifyVector(t, v, expected)
+		}
+	}
+	verifyVector(t, v, expected)
+}
+
+func TestVectorAppendRelaxed(t *testing.T) {
+	expected := []int{-3, -2, -1}
+	v := vectorOf(expected)
+	for i := 0; i < 5000; i++ {
+		expected = append(expected, i)
+	}
+	v = v.Concat(vectorOf(expected[3:]))
+	for i := 5000; i < 40000; i++ {
+		v = v.Append(i)
+		expected = append(expected, i)
+		if i%4999 == 0 {
+			verifyVector(t, v, expected)
+			verifyVectorDepth(t, v)
+		}
+	}
+	verifyVector(t, v, expected)
+	verifyVectorDepth(t, v)
+}
+
+// verifyVectorDepth reports an error if the tree is more than one level deeper than
+// the shallowest tree that could hold every value.
+func verifyVectorDepth(t *testing.T, v Vector[int]) {
+	depth := int(v.(*vectorImpl[int]).shift/vectorBits) + 1
+	shallowest := 1
+	for capacity := vectorWidth; capacity < v.Size(); capacity *= vectorWidth {
+		shallowest++
+	}
+	if depth > shallowest+1 {
+		t.Error(fmt.Sprintf("vector too deep: size=%d depth=%d shallowest=%d", v.Size(), depth, shallowest))
+	}
+}
+
+func TestVectorDepth(t *testing.T) {
+	v := NewVector[int]()
+	var expected []int
+	for i := 0; i < 5000; i++ {
+		v = vectorOf([]int{i}).Concat(v)
+		expected = append(expected, 4999-i)
+	}
+	verifyVector(t, v, expected)
+	verifyVectorDepth(t, v)
+
+	random := rand.New(rand.NewSource(27))
+	v = NewVector[int]()
+	expected = nil
+	for i := 0; i < 2000; i++ {
+		other := make([]int, random.Intn(100)+1)
+		for j := range other {
+			other[j] = random.Int()
+		}
+		if random.Intn(2) == 0 {
+			v = v.Concat(vectorOf(other))
+			expected = append(expected, other...)
+		} else {
+			v = vectorOf(other).Concat(v)
+			expected = append(other, expected...)
+		}
+		if random.Intn(10) == 0 && len(expected) < 200000 {
+			from := random.Intn(len(expected) + 1)
+			v = v.Concat(v.Slice(from, len(expected)))
+			expected = append(expected, slices.Clone(expected[from:])...)
+		}
+		if i%250 == 0 {
+			verifyVectorDepth(t, v)
+		}
+	}
+	verifyVector(t, v, expected)
+	verifyVectorDepth(t, v)
+}
+
+func TestVectorIteration(t *testing.T) {
+	v := vectorOf([]int{1, 2, 3}).Concat(vectorOf([]int{4, 5})).Slice(1, 5)
+	all := v.All()
+	for pass := 0; pass < 2; pass++ {
+		answer := "|"
+		for value := range all {
+			answer += fmt.Sprintf("%d|", value)
+		}
+		assertString(answer, "|2|3|4|5|", t)
+	}
+
+	var collection Collection[int] = v
+	if collection.Size() != 4 {
+		t.Error(fmt.Sprintf("expected size 4 but got %d", collection.Size()))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Get with index out of range did not panic")
+		}
+	}()
+	v.Get(4)
+}
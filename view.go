@@ -0,0 +1,93 @@
+package immutableMap
+
+import "iter"
+
+// Entry holds a key and its value from a Map.
+type Entry[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// Collection is a read-only view of values that can be counted and iterated.
+type Collection[T any] interface {
+	Size() int
+	Iterate() Iterator[T]
+	ForEach(v SetVisitor[T])
+	All() iter.Seq[T]
+	ToSlice() []T
+}
+
+// Iterator visits the values of a Collection.  Next advances to the next value and
+// returns false once every value has been visited.  Get returns the current value.
+type Iterator[T any] interface {
+	Next() bool
+	Get() T
+}
+
+// nodeView presents the entries of a trie as a Collection by projecting each key
+// and value into a single value.  It shares the trie rather than copying it.
+type nodeView[K any, V any, T any] struct {
+	root    *node[K, V]
+	size    int
+	project func(K, V) T
+}
+
+type nodeViewIterator[K any, V any, T any] struct {
+	state   *iteratorState[K, V]
+	project func(K, V) T
+	value   T
+}
+
+func projectValue[K any, V any](_ K, value V) V {
+	return value
+}
+
+func projectEntry[K any, V any](key K, value V) Entry[K, V] {
+	return Entry[K, V]{Key: key, Value: value}
+}
+
+func (this *nodeView[K, V, T]) Size() int {
+	return this.size
+}
+
+func (this *nodeView[K, V, T]) Iterate() Iterator[T] {
+	return &nodeViewIterator[K, V, T]{state: this.root.createIteratorState(nil), project: this.project}
+}
+
+func (this *nodeView[K, V, T]) ForEach(v SetVisitor[T]) {
+	this.root.forEach(func(key K, value V) {
+		v(this.project(key, value))
+	})
+}
+
+func (this *nodeView[K, V, T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		this.root.forEachWhile(func(key K, value V) bool {
+			return yield(this.project(key, value))
+		})
+	}
+}
+
+func (this *nodeView[K, V, T]) ToSlice() []T {
+	answer := make([]T, 0, this.size)
+	this.root.forEach(func(key K, value V) {
+		answer = append(answer, this.project(key, value))
+	})
+	return answer
+}
+
+func (this *nodeViewIterator[K, V, T]) Next() bool {
+	if this.state == nil {
+		return false
+	} else {
+		var key K
+		var value V
+		this.state, key, value = this.state.currentNode.next(this.state)
+		this.value = this.project(key, value)
+		return true
+	}
+}
+
+func (this *nodeViewIterator[K, V, T]) Get() T {
+	return this.value
+}